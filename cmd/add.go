@@ -0,0 +1,313 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	psaapi "k8s.io/pod-security-admission/api"
+)
+
+var (
+	containerOpts *k8s.EphemeralContainerOptions = &k8s.EphemeralContainerOptions{}
+
+	containerNameUsage   string = "Name of the ephemeral container. If unset, a name is generated with prefix \"debugger-\""
+	imageUsage           string = "Container image to use for the ephemeral container"
+	imagePullPolicyUsage string = "Image pull policy for the ephemeral container. One of: Always, IfNotPresent, Never. If unset, the server default is used"
+	targetUsage          string = "Name of the container in the pod to target. The ephemeral container shares the process namespace of the target if supported by the container runtime"
+	envUsage             string = "Environment variables to set in the ephemeral container in the form of KEY=VALUE. Can be repeated"
+	stdinUsage           string = "If true, keep stdin open on the ephemeral container"
+	ttyUsage             string = "If true, allocate a TTY for the ephemeral container"
+
+	concurrency      int
+	concurrencyUsage string = "Maximum number of Pods to update concurrently in bulk mode"
+
+	qps      float32
+	qpsUsage string = "Maximum number of update requests per second in bulk mode"
+
+	maxPods      int
+	maxPodsUsage string = "Maximum number of Pods to update in bulk mode. The command fails without any changes if more Pods are matched"
+)
+
+func NewAddCmd() *cobra.Command {
+	addCmd := &cobra.Command{
+		Use:   "add",
+		Short: "Command to add an ephemeral container to a Pod without an editor",
+		Long: `
+This command constructs an ephemeral container from flags and adds it to a Pod via the pod's ephemeralcontainers subresource.
+
+Arguments after "--" are used as the command of the ephemeral container.
+Debug profiles (i.e. --profile) fill in the fields that are not set with flags.
+If --as-target is set, the ephemeral container runs with the same user and group as the target container (e.g. to debug file permissions).
+With --as-target, the first container in the pod is targeted if no target is set with --target or a profile.
+A partial container spec (i.e. --custom) is strategic-merged on top, like "kubectl debug --custom".
+If --dry-run is set, the ephemeral containers that would be submitted (i.e. client) or the server-defaulted result (i.e. server) are printed instead. For example:
+
+	kubectl ephemeral-containers add pod/web --image busybox --name dbg --target app --env KEY=VALUE -- sh -c 'sleep 3600'
+
+The ephemeral container is added to all matching Pods (i.e. bulk mode) if --selector, --all-namespaces, a name pattern (e.g. "pod/web-*")
+or a workload (e.g. "deploy/web", "sts/db") is set.
+Pods that already have an ephemeral container with the same name are skipped. For example:
+
+	kubectl ephemeral-containers add -l app=web --profile netshoot --name netshoot
+
+New ephemeral containers are validated against the Pod Security level enforced in the namespace before submitting. If a compliant variant exists, it is offered (see --pod-security).
+In bulk mode, pods with violations fail unless --pod-security=adjust is set.
+	`,
+		// Format: "kind/name", "kind name", "pod-name" followed by an optional "-- command"
+		// In bulk mode, the pod name (or pattern) is optional
+		Args: func(cmd *cobra.Command, args []string) error {
+			podArgs, _ := splitArgsAtDash(cmd, args)
+			if len(labelSelector) > 0 || allNamespace {
+				return cobra.RangeArgs(0, 2)(cmd, podArgs)
+			}
+			return cobra.RangeArgs(1, 2)(cmd, podArgs)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			strategy := getDryRunStrategy()
+			if err := validatePodSecurityMode(); err != nil {
+				ExitError(err, 1)
+			}
+			loadCustomContainer()
+			podArgs, command := splitArgsAtDash(cmd, args)
+
+			ref := &k8s.ResourceRef{Kind: k8s.KindPod}
+			if len(podArgs) > 0 {
+				var err error
+				if ref, err = k8s.GetResourceRefFromArgs(podArgs); err != nil {
+					ExitError(err, 1)
+				}
+			}
+
+			client, err := k8s.NewClientset(kubeConfig)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			containerOpts.Command = command
+			if !ref.IsPod() {
+				pods, err := client.GetPodsForWorkload(kubeConfig.ContextOptions, *kubeConfig.Namespace, ref)
+				if err != nil {
+					ExitError(err, 1)
+				}
+				addEphemeralContainerToPods(client, pods, strategy)
+				return
+			}
+
+			if len(labelSelector) > 0 || allNamespace || k8s.IsNamePattern(ref.Name) {
+				addEphemeralContainerToPods(client, listPodsForBulk(client, ref.Name), strategy)
+				return
+			}
+
+			podName := ref.Name
+			pod, err := client.GetPod(kubeConfig.ContextOptions, *kubeConfig.Namespace, podName)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			container, err := k8s.NewEphemeralContainer(containerOpts)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			p, err := resolveProfiles()
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			if err = applyProfiles(p, container, pod, out.ErrLn); err != nil {
+				ExitError(err, 1)
+			}
+
+			editedPod, err := k8s.AddEphemeralContainer(pod, container)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			patch, err := k8s.SanitizeEditedPod(pod, editedPod)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			if patch, err = checkPodSecurity(client, pod, patch); err != nil {
+				ExitError(err, 1)
+			}
+
+			if err = updateEphemeralContainers(client, patch, strategy); err != nil {
+				ExitError(errors.Join(fmt.Errorf("failed to add ephemeral container %s to pod/%s", container.Name, podName), err), 1)
+			}
+
+			if strategy == k8s.DryRunNone {
+				out.Ln("ephemeral container %s added to pod/%s", container.Name, podName)
+			}
+		},
+	}
+
+	addCmd.Flags().StringVarP(&containerOpts.Image, "image", "", "", imageUsage)
+	addCmd.Flags().StringVarP(&containerOpts.Name, "name", "", "", containerNameUsage)
+	addCmd.Flags().StringVarP(&containerOpts.ImagePullPolicy, "image-pull-policy", "", "", imagePullPolicyUsage)
+	addCmd.Flags().StringVarP(&containerOpts.TargetContainer, "target", "", "", targetUsage)
+	addCmd.Flags().StringArrayVarP(&containerOpts.Env, "env", "", nil, envUsage)
+	addCmd.Flags().BoolVarP(&containerOpts.Stdin, "stdin", "i", false, stdinUsage)
+	addCmd.Flags().BoolVarP(&containerOpts.TTY, "tty", "t", false, ttyUsage)
+	addCmd.Flags().StringVarP(&dryRun, "dry-run", "", string(k8s.DryRunNone), dryRunUsage)
+	addCmd.Flags().StringSliceVarP(&profileNames, "profile", "", nil, profileNamesUsage)
+	addCmd.Flags().StringVarP(&customFile, "custom", "", "", customFileUsage)
+	addCmd.Flags().BoolVarP(&asTarget, "as-target", "", false, asTargetUsage)
+	addCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", labelSelectorUsage)
+	addCmd.Flags().BoolVarP(&allNamespace, "all-namespaces", "A", false, allNamespaceUsage)
+	addCmd.Flags().IntVarP(&concurrency, "concurrency", "", k8s.DEFAULT_BULK_CONCURRENCY, concurrencyUsage)
+	addCmd.Flags().Float32VarP(&qps, "qps", "", k8s.DEFAULT_BULK_QPS, qpsUsage)
+	addCmd.Flags().IntVarP(&maxPods, "max-pods", "", k8s.DEFAULT_BULK_MAX_PODS, maxPodsUsage)
+	addCmd.Flags().StringVarP(&podSecurity, "pod-security", "", podSecurityPrompt, podSecurityUsage)
+
+	return addCmd
+}
+
+// Split arguments into the ones before and after "--" (if any)
+func splitArgsAtDash(cmd *cobra.Command, args []string) ([]string, []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil
+	}
+	return args[:dash], args[dash:]
+}
+
+// List all pods matching --selector and the name pattern (if any)
+func listPodsForBulk(client *k8s.KubeClientset, pattern string) []corev1.Pod {
+	namespace := *kubeConfig.Namespace
+	if allNamespace {
+		namespace = ""
+	}
+
+	var filters []k8s.PodFilterFn
+	if len(pattern) > 0 {
+		filters = append(filters, k8s.NamePatternFilter(pattern))
+	}
+
+	pods, err := client.ListPods(kubeConfig.ContextOptions, namespace, metav1.ListOptions{LabelSelector: labelSelector, Limit: k8s.DEFAULT_CHUNK_SIZE}, filters...)
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	return pods
+}
+
+// Add the ephemeral container to all pods
+// Exit non-zero if the ephemeral container cannot be added to any pod
+func addEphemeralContainerToPods(client *k8s.KubeClientset, pods []corev1.Pod, strategy k8s.DryRunStrategy) {
+	if len(pods) == 0 {
+		ExitError(errors.New("no pods found"), 1)
+	}
+
+	if len(pods) > maxPods {
+		ExitError(fmt.Errorf("%d pods matched, which exceeds --max-pods=%d. Narrow down the selection or raise --max-pods", len(pods), maxPods), 1)
+	}
+
+	// Generate the ephemeral container once so that it has the same name in all pods
+	base, err := k8s.NewEphemeralContainer(containerOpts)
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	// Profiles are resolved once before updating pods concurrently
+	p, err := resolveProfiles()
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	// Pod Security levels are read once per namespace before updating pods concurrently
+	levels := map[string]*psaapi.LevelVersion{}
+	for _, pod := range pods {
+		if _, found := levels[pod.Namespace]; !found {
+			levels[pod.Namespace] = getPodSecurityEnforcement(client, pod.Namespace)
+		}
+	}
+
+	// Warnings are collected per pod and printed after the results so that they do not interleave
+	var mu sync.Mutex
+	warnings := map[string][]string{}
+
+	results := client.AddEphemeralContainerToPods(kubeConfig.ContextOptions, pods, func(pod *corev1.Pod) (*corev1.EphemeralContainer, error) {
+		container := base.DeepCopy()
+		err := applyProfiles(p, container, pod, func(format string, a ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			key := pod.Namespace + "/" + pod.Name
+			warnings[key] = append(warnings[key], fmt.Sprintf(format, a...))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return checkPodSecurityForBulk(levels[pod.Namespace], pod, container)
+	}, k8s.BulkOptions{
+		Concurrency: concurrency,
+		QPS:         qps,
+		DryRun:      strategy,
+	})
+
+	data := make([]formatter.BulkResultData, 0, len(results))
+	for _, result := range results {
+		d := formatter.BulkResultData{
+			Name:      result.Pod,
+			Namespace: result.Namespace,
+			Container: result.Container,
+			Result:    string(result.Result),
+		}
+		if result.Err != nil {
+			d.Message = result.Err.Error()
+		}
+		data = append(data, d)
+	}
+
+	output, err := formatter.FormatBulkResultOutput(outputFormat, data)
+	if err != nil {
+		ExitError(err, 1)
+	}
+	out.Ln("%s", output)
+
+	for _, result := range results {
+		for _, warning := range warnings[result.Namespace+"/"+result.Pod] {
+			out.ErrLn("%s", warning)
+		}
+	}
+
+	summary, failed := k8s.SummarizeBulkResults(results)
+	if strategy != k8s.DryRunNone {
+		summary += fmt.Sprintf(" (dry run: %s)", strategy)
+	}
+
+	// Summary is sent to stderr to keep the output parsable
+	if failed > 0 {
+		ExitError(errors.New(summary), 1)
+	}
+	out.ErrLn("%s", summary)
+}
@@ -0,0 +1,159 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	filenames     []string
+	filenameUsage string = "Files containing the ephemeral containers to add. Use \"-\" to read from stdin. Can be repeated"
+)
+
+func NewApplyCmd() *cobra.Command {
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Command to add ephemeral containers to a Pod from manifests",
+		Long: `
+This command adds the ephemeral containers described in manifests to a Pod via the pod's ephemeralcontainers subresource.
+
+A manifest (YAML or JSON) can contain multiple documents. Each document is one of:
+  * An ephemeral container
+  * A list of ephemeral containers
+  * A (partial) Pod with only "spec.ephemeralContainers"
+
+Ephemeral containers that already exist in the Pod with an identical spec are skipped.
+
+If --dry-run is set, the ephemeral containers that would be submitted (i.e. client) or the server-defaulted result (i.e. server) are printed instead.
+
+New ephemeral containers are validated against the Pod Security level enforced in the namespace before submitting. If a compliant variant exists, it is offered (see --pod-security).
+	`,
+		// Format: "kind/name", "kind name", "pod-name"
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			strategy := getDryRunStrategy()
+			if err := validatePodSecurityMode(); err != nil {
+				ExitError(err, 1)
+			}
+
+			if len(filenames) == 0 {
+				ExitError(errors.New("at least one manifest must be specified with --filename"), 1)
+			}
+
+			containers, err := readEphemeralContainers(filenames)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			client, err := k8s.NewClientset(kubeConfig)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			pod, err := getPodFromArgs(client, args)
+			if err != nil {
+				ExitError(err, 1)
+			}
+			podName := pod.Name
+
+			editedPod, added, skipped, err := k8s.MergeEphemeralContainers(pod, containers)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			patch, err := k8s.SanitizeEditedPod(pod, editedPod)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			if patch != nil {
+				if patch, err = checkPodSecurity(client, pod, patch); err != nil {
+					ExitError(err, 1)
+				}
+
+				if err = updateEphemeralContainers(client, patch, strategy); err != nil {
+					ExitError(errors.Join(fmt.Errorf("failed to apply ephemeral containers to pod/%s", podName), err), 1)
+				}
+			}
+
+			if strategy != k8s.DryRunNone {
+				return
+			}
+
+			for _, name := range added {
+				out.Ln("ephemeral container %s added to pod/%s", name, podName)
+			}
+			for _, name := range skipped {
+				out.Ln("ephemeral container %s unchanged in pod/%s", name, podName)
+			}
+		},
+	}
+
+	applyCmd.Flags().StringSliceVarP(&filenames, "filename", "f", nil, filenameUsage)
+	applyCmd.Flags().StringVarP(&dryRun, "dry-run", "", string(k8s.DryRunNone), dryRunUsage)
+	applyCmd.Flags().BoolVarP(&choosePod, "choose-pod", "", false, choosePodUsage)
+	applyCmd.Flags().StringVarP(&podSecurity, "pod-security", "", podSecurityPrompt, podSecurityUsage)
+
+	return applyCmd
+}
+
+// Read ephemeral containers from files. The filename "-" is stdin
+func readEphemeralContainers(filenames []string) ([]corev1.EphemeralContainer, error) {
+	var containers []corev1.EphemeralContainer
+
+	for _, filename := range filenames {
+		parsed, err := readEphemeralContainersFromFile(filename)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("failed to read ephemeral containers from %s", filename), err)
+		}
+		containers = append(containers, parsed...)
+	}
+
+	return containers, nil
+}
+
+// Read ephemeral containers from a single file. The filename "-" is stdin
+func readEphemeralContainersFromFile(filename string) (containers []corev1.EphemeralContainer, err error) {
+	var reader io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			err = errors.Join(err, f.Close())
+		}()
+		reader = f
+	}
+
+	return k8s.ParseEphemeralContainers(reader)
+}
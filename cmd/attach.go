@@ -0,0 +1,128 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	streamStdin      bool
+	streamStdinUsage string = "Pass stdin to the ephemeral container"
+
+	streamTTY      bool
+	streamTTYUsage string = "Stdin is a TTY"
+)
+
+func NewAttachCmd() *cobra.Command {
+	attachCmd := &cobra.Command{
+		Use:   "attach",
+		Short: "Attach to a running ephemeral container in a Pod",
+		Long: `
+Attach to a running ephemeral container in a Pod.
+
+If --container is unset and the Pod has exactly one running ephemeral container, that container is selected.
+If --stdin and --tty are unset, they default to the ephemeral container's spec.
+	`,
+		// Format: "kind/name", "kind name", "pod-name"
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, pod, containerName := getRunningEphemeralContainer(args)
+
+			if container := k8s.FindEphemeralContainer(pod, containerName); container != nil {
+				if !cmd.Flags().Changed("stdin") {
+					streamStdin = container.Stdin
+				}
+				if !cmd.Flags().Changed("tty") {
+					streamTTY = container.TTY
+				}
+			}
+
+			opts := newStreamOptions(pod, containerName)
+			if opts.TTY {
+				out.ErrLn("If you don't see a command prompt, try pressing enter.")
+			}
+
+			if err := client.Attach(kubeConfig.ContextOptions, opts); err != nil {
+				ExitError(err, 1)
+			}
+		},
+	}
+
+	attachCmd.Flags().StringVarP(&ephContainerName, "container", "c", "", ephContainerNameUsage)
+	attachCmd.Flags().BoolVarP(&streamStdin, "stdin", "i", false, streamStdinUsage)
+	attachCmd.Flags().BoolVarP(&streamTTY, "tty", "t", false, streamTTYUsage)
+	attachCmd.Flags().BoolVarP(&choosePod, "choose-pod", "", false, choosePodUsage)
+
+	return attachCmd
+}
+
+// Get the pod and a running ephemeral container from arguments and --container
+func getRunningEphemeralContainer(args []string) (*k8s.KubeClientset, *corev1.Pod, string) {
+	client, err := k8s.NewClientset(kubeConfig)
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	pod, err := getPodFromArgs(client, args)
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	containerName := ephContainerName
+	if len(containerName) == 0 {
+		if containerName, err = k8s.SelectRunningEphemeralContainer(pod); err != nil {
+			ExitError(err, 1)
+		}
+	} else if err = k8s.ValidateRunningEphemeralContainer(pod, containerName); err != nil {
+		ExitError(err, 1)
+	}
+
+	return client, pod, containerName
+}
+
+// Construct stream options for the container with stdin/stdout/stderr of the process
+func newStreamOptions(pod *corev1.Pod, containerName string) *k8s.StreamOptions {
+	opts := &k8s.StreamOptions{
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Container: containerName,
+		Stdin:     streamStdin,
+		TTY:       streamTTY,
+		In:        os.Stdin,
+		Out:       out.GetOutFile(),
+		ErrOut:    out.GetErrFile(),
+	}
+
+	if opts.TTY && !(opts.Stdin && k8s.IsTerminal(opts.In)) {
+		out.ErrLn("Unable to use a TTY - input is not a terminal or stdin is not requested")
+		opts.TTY = false
+	}
+
+	return opts
+}
@@ -40,7 +40,7 @@ var _ = Describe("Cmd", func() {
 	Context("root command", func() {
 		BeforeEach(func() {
 			t.cmd = cmd.NewRootCmd()
-			t.subCmds = []string{"edit", "list", "version"}
+			t.subCmds = []string{"add", "apply", "attach", "describe", "doctor", "edit", "exec", "list", "logs", "profiles", "version", "wait"}
 		})
 
 		It("should have basic configurations", func() {
@@ -91,7 +91,164 @@ var _ = Describe("Cmd", func() {
 		})
 
 		It("should have local flags", func() {
-			for _, flag := range []string{"editor", "minify"} {
+			for _, flag := range []string{"editor", "minify", "profile", "custom", "as-target", "dry-run", "yes", "choose-pod", "pod-security"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
+
+	Context("add command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewAddCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		Context("when given arguments", func() {
+			It("should accept 1 argument", func() {
+				err := t.cmd.Args(t.cmd, []string{"pod/name"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should accept 2 arguments", func() {
+				err := t.cmd.Args(t.cmd, []string{"pods", "pod-name"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail otherwise", func() {
+				err := t.cmd.Args(t.cmd, []string{})
+				Expect(err).To(HaveOccurred())
+
+				err = t.cmd.Args(t.cmd, []string{"pods", "pod-name", "another-one"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"image", "name", "image-pull-policy", "target", "env", "stdin", "tty", "profile", "custom", "as-target", "dry-run", "selector", "all-namespaces", "concurrency", "qps", "max-pods", "pod-security"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
+
+	Context("apply command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewApplyCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		Context("when given arguments", func() {
+			It("should accept 1 argument", func() {
+				err := t.cmd.Args(t.cmd, []string{"pod/name"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should accept 2 arguments", func() {
+				err := t.cmd.Args(t.cmd, []string{"pods", "pod-name"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail otherwise", func() {
+				err := t.cmd.Args(t.cmd, []string{})
+				Expect(err).To(HaveOccurred())
+
+				err = t.cmd.Args(t.cmd, []string{"pods", "pod-name", "another-one"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"filename", "dry-run", "choose-pod", "pod-security"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
+
+	Context("attach command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewAttachCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"container", "stdin", "tty", "choose-pod"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
+
+	Context("describe command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewDescribeCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		It("should have alias status", func() {
+			Expect(t.cmd.Aliases).To(ContainElement("status"))
+		})
+
+		Context("when given arguments", func() {
+			It("should accept 1 argument", func() {
+				err := t.cmd.Args(t.cmd, []string{"pod/name"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail otherwise", func() {
+				err := t.cmd.Args(t.cmd, []string{})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"choose-pod"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
+
+	Context("doctor command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewDoctorCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		It("should accept no arguments", func() {
+			Expect(t.cmd.Args(t.cmd, []string{})).ToNot(HaveOccurred())
+			Expect(t.cmd.Args(t.cmd, []string{"pod/name"})).To(HaveOccurred())
+		})
+	})
+
+	Context("exec command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewExecCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		Context("when given arguments", func() {
+			It("should accept 1 argument", func() {
+				err := t.cmd.Args(t.cmd, []string{"pod/name"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should fail otherwise", func() {
+				err := t.cmd.Args(t.cmd, []string{})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"container", "stdin", "tty", "choose-pod"} {
 				t.expectFlag(flag, false)
 			}
 		})
@@ -106,13 +263,68 @@ var _ = Describe("Cmd", func() {
 			t.expectCmdBasics()
 		})
 
+		Context("when given arguments", func() {
+			It("should accept 0 to 2 arguments", func() {
+				for _, args := range [][]string{{}, {"deploy/web"}, {"sts", "db"}} {
+					Expect(t.cmd.Args(t.cmd, args)).ToNot(HaveOccurred())
+				}
+			})
+			It("should fail otherwise", func() {
+				err := t.cmd.Args(t.cmd, []string{"deploy", "web", "another-one"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
 		It("should have local flags", func() {
-			for _, flag := range []string{"all-namespaces"} {
+			for _, flag := range []string{"all-namespaces", "selector", "field-selector", "chunk-size", "watch", "per-container"} {
 				t.expectFlag(flag, false)
 			}
 		})
 	})
 
+	Context("logs command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewLogsCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		Context("when given arguments", func() {
+			It("should accept 0 to 2 arguments", func() {
+				for _, args := range [][]string{{}, {"pod/name"}, {"pods", "pod-name"}} {
+					Expect(t.cmd.Args(t.cmd, args)).ToNot(HaveOccurred())
+				}
+			})
+			It("should fail otherwise", func() {
+				err := t.cmd.Args(t.cmd, []string{"pods", "pod-name", "another-one"})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"container", "follow", "since", "selector", "all-namespaces"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
+
+	Context("profiles command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewProfilesCmd()
+			t.subCmds = []string{"list", "show"}
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		It("should have subcommands", func() {
+			t.expectSubCommands()
+		})
+	})
+
 	Context("version command", func() {
 		BeforeEach(func() {
 			t.cmd = cmd.NewVersionCmd()
@@ -126,6 +338,22 @@ var _ = Describe("Cmd", func() {
 			Expect(t.cmd.HasLocalFlags()).To(BeFalse())
 		})
 	})
+
+	Context("wait command", func() {
+		BeforeEach(func() {
+			t.cmd = cmd.NewWaitCmd()
+		})
+
+		It("should have basic configurations", func() {
+			t.expectCmdBasics()
+		})
+
+		It("should have local flags", func() {
+			for _, flag := range []string{"container", "for", "choose-pod"} {
+				t.expectFlag(flag, false)
+			}
+		})
+	})
 })
 
 type test struct {
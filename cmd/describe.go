@@ -0,0 +1,68 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+)
+
+func NewDescribeCmd() *cobra.Command {
+	describeCmd := &cobra.Command{
+		Use:     "describe",
+		Aliases: []string{"status"},
+		Short:   "Show the spec and state of ephemeral containers in a Pod",
+		Long: `
+Show the spec and state of ephemeral containers in a Pod.
+
+For each ephemeral container, the output includes the image, target container, command, state (Waiting, Running, Terminated),
+reason, exit code, start and finish times. Ephemeral containers without a reported status are in state Pending.
+	`,
+		// Format: "kind/name", "kind name", "pod-name"
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := k8s.NewClientset(kubeConfig)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			pod, err := getPodFromArgs(client, args)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			output, err := formatter.FormatDescribeOutput(outputFormat, *pod)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			out.Ln("%s", output)
+		},
+	}
+
+	describeCmd.Flags().BoolVarP(&choosePod, "choose-pod", "", false, choosePodUsage)
+
+	return describeCmd
+}
@@ -0,0 +1,79 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+)
+
+func NewDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check whether the cluster and your permissions are ready for ephemeral containers",
+		Long: `
+Check whether the cluster and your permissions are ready for ephemeral containers in the current namespace.
+
+The following checks are reported as Pass, Warn or Fail with hints to fix the issues:
+  - The server version is at least ` + k8s.MIN_SERVER_VERSION + `
+  - The API server serves the subresource pods/ephemeralcontainers
+  - Permissions (i.e. SelfSubjectAccessReviews) to get and list pods, update and patch pods/ephemeralcontainers, attach to pods and read pod logs
+  - The Pod Security level enforced in the namespace
+
+The command exits non-zero if any check fails.
+	`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := k8s.NewClientset(kubeConfig)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			results := client.RunPreflightChecks(kubeConfig.ContextOptions, *kubeConfig.Namespace)
+
+			data := make([]formatter.DoctorCheckData, 0, len(results))
+			for _, result := range results {
+				data = append(data, formatter.DoctorCheckData{
+					Check:   result.Name,
+					Status:  string(result.Status),
+					Message: result.Message,
+					Hint:    result.Hint,
+				})
+			}
+
+			output, err := formatter.FormatDoctorOutput(outputFormat, data)
+			if err != nil {
+				ExitError(err, 1)
+			}
+			out.Ln("%s", output)
+
+			if k8s.HasFailedCheck(results) {
+				ExitError(errors.New("one or more checks failed"), 1)
+			}
+		},
+	}
+}
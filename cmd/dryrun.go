@@ -0,0 +1,76 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	dryRun      string
+	dryRunUsage string = fmt.Sprintf("Must be \"%s\", \"%s\" or \"%s\". If %s, only print the ephemeral containers that would be submitted. If %s, submit a server-side request without persisting it and print the result",
+		k8s.DryRunNone, k8s.DryRunClient, k8s.DryRunServer, k8s.DryRunClient, k8s.DryRunServer)
+)
+
+// Get the dry-run strategy from --dry-run
+func getDryRunStrategy() k8s.DryRunStrategy {
+	strategy, err := k8s.ParseDryRunStrategy(dryRun)
+	if err != nil {
+		ExitError(err, 1)
+	}
+	return strategy
+}
+
+// Submit the patch to the pod's ephemeralcontainers subresource according to the dry-run strategy
+// In dry-run modes, the ephemeral containers that would be submitted (or defaulted by the server) are printed
+func updateEphemeralContainers(client *k8s.KubeClientset, patch *corev1.Pod, strategy k8s.DryRunStrategy) error {
+	switch strategy {
+	case k8s.DryRunClient:
+		return printDryRunResult(patch)
+	case k8s.DryRunServer:
+		result, err := client.UpdateEphemeralContainersForPod(kubeConfig.ContextOptions, patch, k8s.WithDryRun())
+		if err != nil {
+			return err
+		}
+		return printDryRunResult(k8s.MinifyPod(result))
+	default:
+		_, err := client.UpdateEphemeralContainersForPod(kubeConfig.ContextOptions, patch)
+		return err
+	}
+}
+
+// Print the dry-run result in the output format
+func printDryRunResult(pod *corev1.Pod) error {
+	output, err := formatter.FormatPodOutput(outputFormat, pod)
+	if err != nil {
+		return err
+	}
+
+	out.Ln("%s", output)
+	return nil
+}
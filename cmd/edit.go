@@ -27,10 +27,12 @@ import (
 	"fmt"
 
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/edit"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/printers"
 )
 
 var (
@@ -39,6 +41,11 @@ var (
 
 	minify      bool
 	minifyUsage string = "If true, remove information not necessary for editting ephemeral containers. Default to false"
+
+	yes      bool
+	yesUsage string = "If true, submit the changes without showing the diff and asking for confirmation"
+
+	scaffoldProfileUsage string = "Names of debug profiles (including the built-in ones, e.g. netadmin) to expand into a new ephemeral container added to the editor buffer as a scaffold. Can be repeated"
 )
 
 func NewEditCmd() *cobra.Command {
@@ -49,44 +56,104 @@ func NewEditCmd() *cobra.Command {
 This command is a convenient wrapper that, in turn, uses the pod's ephemeralcontainers subresource.
 
 Note: The command only consider changes to "pod.spec.ephemeralContainers". Other changes are ignored.
+
+If the edited content is invalid or rejected by the API server, the editor is reopened with the error as a comment header.
+Save an empty file to abort the edit. If the file is saved unchanged after an error, or the editor exits with an error or is interrupted, it is kept in a temporary location for recovery.
+
+Before submitting, a diff of "pod.spec.ephemeralContainers" is shown with a confirmation prompt. Set --yes to skip the prompt.
+Reordering existing ephemeral containers is ignored with a warning.
+
+If --dry-run is set, the ephemeral containers that would be submitted (i.e. client) or the server-defaulted result (i.e. server) are printed instead.
+
+New ephemeral containers are validated against the Pod Security level enforced in the namespace before submitting. If a compliant variant exists, it is offered (see --pod-security).
+
+If --profile (or --custom) is set, a new ephemeral container expanded from the profiles (and the partial container spec) is added to the editor buffer as a scaffold.
+If --as-target is set, the scaffold runs with the same user and group as its target container (the first container in the pod by default).
 	`,
-		// Format: "pod/pod-name", "pod pod-name", "pod-name"
+		// Format: "kind/name", "kind name", "pod-name"
 		Args: cobra.RangeArgs(1, 2),
 		Run: func(cmd *cobra.Command, args []string) {
-			podName, err := k8s.GetPodNameFromArgs(args)
-			if err != nil {
+			strategy := getDryRunStrategy()
+			if err := validatePodSecurityMode(); err != nil {
 				ExitError(err, 1)
 			}
+			loadCustomContainer()
 
 			client, err := k8s.NewClientset(kubeConfig)
 			if err != nil {
 				ExitError(err, 1)
 			}
 
-			pod, err := client.GetPod(kubeConfig.ContextOptions, *kubeConfig.Namespace, podName)
+			pod, err := getPodFromArgs(client, args)
 			if err != nil {
 				ExitError(err, 1)
 			}
+			podName := pod.Name
+
+			// Generate the scaffold with the full pod spec (i.e. before minifying)
+			var scaffold *corev1.EphemeralContainer
+			if len(profileNames) > 0 || customContainer != nil || asTarget {
+				if scaffold, err = newScaffoldContainer(pod); err != nil {
+					ExitError(err, 1)
+				}
+			}
 
+			// Pod Security is evaluated with the full pod spec (i.e. before minifying)
+			fullPod := pod
 			if minify {
 				pod = k8s.MinifyPod(pod)
 			}
 
-			editedPod, err := edit.EditResource(kubeConfig.ContextOptions, edit.GetEditorCmd(editor), pod, &corev1.Pod{})
-			if err != nil {
-				ExitError(errors.Join(fmt.Errorf("failed to edit pod/%s", podName), err), 1)
+			// The scaffold is only added to the editor buffer so that it is considered as a change
+			bufferPod := pod
+			if scaffold != nil {
+				bufferPod = pod.DeepCopy()
+				bufferPod.Spec.EphemeralContainers = append(bufferPod.Spec.EphemeralContainers, *scaffold)
 			}
 
-			patch, err := k8s.SanitizeEditedPod(pod, editedPod)
-			if err != nil {
-				ExitError(err, 1)
+			edited := false
+			err = edit.EditResource(kubeConfig.ContextOptions, edit.GetEditorCmd(editor), bufferPod, &corev1.Pod{}, func(editedPod *corev1.Pod) error {
+				patch, err := k8s.SanitizeEditedPod(pod, editedPod)
+				if err != nil {
+					return err
+				}
+
+				if k8s.DiffEphemeralContainers(pod.Spec.EphemeralContainers, editedPod.Spec.EphemeralContainers).Reordered {
+					out.ErrLn("Warning: ephemeral containers cannot be reordered. The original order in pod/%s is kept", podName)
+				}
+				if patch == nil {
+					return nil
+				}
+
+				if patch, err = checkPodSecurity(client, fullPod, patch); err != nil {
+					return err
+				}
+
+				if strategy == k8s.DryRunNone && !yes {
+					confirmed, err := confirmEdit(pod, patch)
+					if err != nil {
+						return err
+					}
+					if !confirmed {
+						return nil
+					}
+				}
+
+				if err = updateEphemeralContainers(client, patch, strategy); err != nil {
+					return err
+				}
+				edited = true
+
+				return nil
+			})
+			if err != nil && !errors.Is(err, edit.ErrEditCancelled) {
+				ExitError(errors.Join(fmt.Errorf("failed to edit pod/%s", podName), err), 1)
 			}
 
-			if patch != nil {
-				if _, err = client.UpdateEphemeralContainersForPod(kubeConfig.ContextOptions, patch); err != nil {
-					ExitError(err, 1)
+			if edited {
+				if strategy == k8s.DryRunNone {
+					out.Ln("pod/%s successfully edited", podName)
 				}
-				out.Ln("pod/%s successfully edited", podName)
 			} else {
 				out.Ln("Edit cancelled, no changes made for pod/%s", podName)
 			}
@@ -96,6 +163,47 @@ Note: The command only consider changes to "pod.spec.ephemeralContainers". Other
 	// Set default to empty to allow search in env vars
 	editCmd.Flags().StringVarP(&editor, "editor", "e", "", editorUsage)
 	editCmd.Flags().BoolVarP(&minify, "minify", "", false, minifyUsage)
+	editCmd.Flags().BoolVarP(&yes, "yes", "y", false, yesUsage)
+	editCmd.Flags().StringVarP(&dryRun, "dry-run", "", string(k8s.DryRunNone), dryRunUsage)
+	editCmd.Flags().StringSliceVarP(&profileNames, "profile", "", nil, scaffoldProfileUsage)
+	editCmd.Flags().StringVarP(&customFile, "custom", "", "", customFileUsage)
+	editCmd.Flags().BoolVarP(&asTarget, "as-target", "", false, asTargetUsage)
+	editCmd.Flags().BoolVarP(&choosePod, "choose-pod", "", false, choosePodUsage)
+	editCmd.Flags().StringVarP(&podSecurity, "pod-security", "", podSecurityPrompt, podSecurityUsage)
 
 	return editCmd
 }
+
+// Generate an ephemeral container from profiles as a scaffold for editing
+func newScaffoldContainer(pod *corev1.Pod) (*corev1.EphemeralContainer, error) {
+	container, err := k8s.NewEphemeralContainer(&k8s.EphemeralContainerOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := resolveProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = applyProfiles(p, container, pod, out.ErrLn); err != nil {
+		return nil, err
+	}
+
+	return container, nil
+}
+
+// Show the diff between the original pod and the patch, then ask for confirmation
+func confirmEdit(original, patch *corev1.Pod) (bool, error) {
+	diff, err := k8s.UnifiedDiffEphemeralContainers(original, patch)
+	if err != nil {
+		return false, err
+	}
+
+	if printers.AllowsColorOutput(out.GetOutFile()) {
+		diff = formatter.ColorizeDiff(diff)
+	}
+	out.Ln("%s", diff)
+
+	return out.Confirm("Apply these changes?")
+}
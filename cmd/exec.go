@@ -0,0 +1,78 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func NewExecCmd() *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:   "exec",
+		Short: "Execute a command in a running ephemeral container in a Pod",
+		Long: `
+Execute a command in a running ephemeral container in a Pod.
+
+Arguments after "--" are used as the command. For example:
+
+	kubectl ephemeral-containers exec pod/web -c debugger -it -- sh
+
+If --container is unset and the Pod has exactly one running ephemeral container, that container is selected.
+	`,
+		// Format: "kind/name", "kind name", "pod-name" followed by "-- command"
+		Args: func(cmd *cobra.Command, args []string) error {
+			podArgs, _ := splitArgsAtDash(cmd, args)
+			return cobra.RangeArgs(1, 2)(cmd, podArgs)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			podArgs, command := splitArgsAtDash(cmd, args)
+			if len(command) == 0 {
+				ExitError(errors.New("command must be specified after \"--\""), 1)
+			}
+
+			client, pod, containerName := getRunningEphemeralContainer(podArgs)
+
+			opts := newStreamOptions(pod, containerName)
+			opts.Command = command
+
+			if err := client.Exec(kubeConfig.ContextOptions, opts); err != nil {
+				// Propagate the exit code of the command
+				var exitErr utilexec.ExitError
+				if errors.As(err, &exitErr) && exitErr.Exited() {
+					ExitError(err, exitErr.ExitStatus())
+				}
+				ExitError(err, 1)
+			}
+		},
+	}
+
+	execCmd.Flags().StringVarP(&ephContainerName, "container", "c", "", ephContainerNameUsage)
+	execCmd.Flags().BoolVarP(&streamStdin, "stdin", "i", false, streamStdinUsage)
+	execCmd.Flags().BoolVarP(&streamTTY, "tty", "t", false, streamTTYUsage)
+	execCmd.Flags().BoolVarP(&choosePod, "choose-pod", "", false, choosePodUsage)
+
+	return execCmd
+}
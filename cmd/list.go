@@ -23,11 +23,17 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
 )
 
 var (
@@ -37,6 +43,18 @@ var (
 
 	allNamespace      bool
 	allNamespaceUsage = "If true, list the pods in all namespaces"
+
+	chunkSize      int64
+	chunkSizeUsage string = "Return large lists in chunks rather than all at once. Pass 0 to disable"
+
+	watchPods      bool
+	watchPodsUsage string = "If true, after listing the Pods, watch for changes to their ephemeral containers"
+
+	perContainer      bool
+	perContainerUsage string = "If true, print a row per ephemeral container (i.e. namespace, pod, container, image, target, state, startedAt and exitCode) instead of a row per Pod"
+
+	fieldSelector      string
+	fieldSelectorUsage string = "Selector (field query) to filter Pods on, supports '=', '==', and '!=' (e.g. --field-selector spec.nodeName=node-1). The server only supports a limited number of field queries per type"
 )
 
 func NewListCmd() *cobra.Command {
@@ -44,25 +62,80 @@ func NewListCmd() *cobra.Command {
 		Use:   "list",
 		Short: "List the Pods with ephemeral containers in the current namespace",
 		Long: `
-List the Pods with ephemeral containers in the current namespace
+List the Pods with ephemeral containers in the current namespace.
+
+Selectors (i.e. --selector and --field-selector) are evaluated by the API server to narrow down the Pods to list.
+Pods are listed in chunks of --chunk-size to limit the load on large clusters.
+
+With "-o wide", the table includes the node, the number of ephemeral containers, their images, targets,
+the number of running and terminated ephemeral containers and the age of the most recently started one.
+kubectl-compatible formats (i.e. name, jsonpath, go-template and custom-columns) print the Pods as a v1 List. For example:
+
+	kubectl ephemeral-containers list -o jsonpath='{.items[*].spec.ephemeralContainers[*].image}'
+	kubectl ephemeral-containers list -o custom-columns=POD:.metadata.name,DEBUGGERS:.spec.ephemeralContainers[*].name
+
+If --per-container is set, a row is printed per ephemeral container instead of per Pod (e.g. for audits).
+kubectl-compatible formats (except name) are then evaluated against a v1 List of the rows (e.g. "jsonpath={.items[*].container}").
+
+If --watch is set, a row (or an event object in JSON and YAML) is printed whenever a Pod gains ephemeral containers or their states change.
+
+If a Pod or a workload (e.g. "deploy/web", "sts/db") is given, only the Pod or the workload's Pods are listed.
 	`,
+		// Format: "kind/name", "kind name", "pod-name" or none
+		Args: cobra.RangeArgs(0, 2),
 		Run: func(cmd *cobra.Command, args []string) {
+			if chunkSize < 0 {
+				ExitError(fmt.Errorf("invalid --chunk-size %d, must be a non-negative integer", chunkSize), 1)
+			}
+
+			if err := formatter.ValidateListFormat(outputFormat); err != nil {
+				ExitError(err, 1)
+			}
+
+			if perContainer && watchPods {
+				ExitError(errors.New("--per-container cannot be combined with --watch"), 1)
+			}
+
 			client, err := k8s.NewClientset(kubeConfig)
 			if err != nil {
 				ExitError(err, 1)
 			}
 
+			if len(args) > 0 {
+				if allNamespace || watchPods || len(labelSelector) > 0 || len(fieldSelector) > 0 {
+					ExitError(errors.New("a Pod or workload cannot be combined with --all-namespaces, --selector, --field-selector or --watch"), 1)
+				}
+
+				if err := listPodsFromArgs(client, args); err != nil {
+					ExitError(err, 1)
+				}
+				return
+			}
+
 			namespace := *kubeConfig.Namespace
 			if allNamespace {
 				namespace = ""
 			}
 
-			pods, err := client.ListPods(kubeConfig.ContextOptions, namespace, filterFn)
+			listOpts := metav1.ListOptions{
+				LabelSelector: labelSelector,
+				FieldSelector: fieldSelector,
+				Limit:         chunkSize,
+			}
+
+			if watchPods {
+				if err := listAndWatchPods(client, namespace, listOpts); err != nil {
+					ExitError(err, 1)
+				}
+				return
+			}
+
+			pods, err := client.ListPods(kubeConfig.ContextOptions, namespace, listOpts, filterFn)
 			if err != nil {
 				ExitError(err, 1)
 			}
 
-			output, err := formatter.FormatListOutput(outputFormat, pods)
+			output, err := formatListOutput(pods)
 			if err != nil {
 				ExitError(err, 1)
 			}
@@ -79,6 +152,100 @@ List the Pods with ephemeral containers in the current namespace
 	}
 
 	listCmd.Flags().BoolVarP(&allNamespace, "all-namespaces", "A", false, allNamespaceUsage)
+	listCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", labelSelectorUsage)
+	listCmd.Flags().StringVarP(&fieldSelector, "field-selector", "", "", fieldSelectorUsage)
+	listCmd.Flags().Int64VarP(&chunkSize, "chunk-size", "", k8s.DEFAULT_CHUNK_SIZE, chunkSizeUsage)
+	listCmd.Flags().BoolVarP(&watchPods, "watch", "w", false, watchPodsUsage)
+	listCmd.Flags().BoolVarP(&perContainer, "per-container", "", false, perContainerUsage)
 
 	return listCmd
 }
+
+// Print the pod (or the workload's pods) with ephemeral containers from arguments
+func listPodsFromArgs(client *k8s.KubeClientset, args []string) error {
+	ref, err := k8s.GetResourceRefFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	pods, err := getPodsFromArgs(client, args)
+	if err != nil {
+		return err
+	}
+
+	output, err := formatListOutput(k8s.ApplyPodFilter(pods, filterFn))
+	if err != nil {
+		return err
+	}
+
+	if len(output) > 0 {
+		out.Ln("%v", output)
+	} else {
+		out.Ln("No pods with ephemeral containers found for %s", ref)
+	}
+	return nil
+}
+
+// Format pods in the output format, with a row per pod or per ephemeral container (i.e. --per-container)
+func formatListOutput(pods []corev1.Pod) (string, error) {
+	if perContainer {
+		return formatter.FormatPerContainerListOutput(outputFormat, pods)
+	}
+	return formatter.FormatListOutput(outputFormat, pods)
+}
+
+// Print the initial list of pods with ephemeral containers, then print changes from a watch until interrupted
+func listAndWatchPods(client *k8s.KubeClientset, namespace string, listOpts metav1.ListOptions) error {
+	pods, resourceVersion, err := client.ListPodsWithResourceVersion(kubeConfig.ContextOptions, namespace, listOpts, filterFn)
+	if err != nil {
+		return err
+	}
+
+	// Last printed ephemeral container states by pod
+	seen := make(map[string]string)
+	for _, pod := range pods {
+		seen[cache.MetaObjectToName(&pod).String()] = formatter.GetEphemeralContainerStates(pod)
+	}
+
+	if err = printWatchEvent(watch.Added, pods, true); err != nil {
+		return err
+	}
+
+	return client.WatchPods(kubeConfig.ContextOptions, namespace, listOpts, resourceVersion, func(eventType watch.EventType, pod *corev1.Pod) error {
+		key := cache.MetaObjectToName(pod).String()
+		last, found := seen[key]
+
+		if eventType == watch.Deleted {
+			if !found {
+				return nil
+			}
+			delete(seen, key)
+			return printWatchEvent(eventType, []corev1.Pod{*pod}, false)
+		}
+
+		if !filterFn(*pod) {
+			return nil
+		}
+
+		states := formatter.GetEphemeralContainerStates(*pod)
+		if found && states == last {
+			return nil
+		}
+		seen[key] = states
+
+		return printWatchEvent(eventType, []corev1.Pod{*pod}, false)
+	})
+}
+
+// Print pod events in the output format
+func printWatchEvent(eventType watch.EventType, pods []corev1.Pod, header bool) error {
+	output, err := formatter.FormatWatchEventOutput(outputFormat, string(eventType), pods, header)
+	if err != nil {
+		return err
+	}
+
+	if len(output) > 0 {
+		out.Ln("%s", output)
+	}
+	return nil
+}
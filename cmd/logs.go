@@ -0,0 +1,113 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	follow      bool
+	followUsage string = "If true, stream new logs as they are written"
+
+	since      time.Duration
+	sinceUsage string = "Only return logs newer than a relative duration like 5s, 2m, or 3h. Defaults to all logs"
+
+	labelSelector      string
+	labelSelectorUsage string = "Selector (label query) to filter Pods on, supports '=', '==', and '!=' (e.g. -l key1=value1,key2=value2)"
+)
+
+func NewLogsCmd() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Print the logs of ephemeral containers",
+		Long: `
+Print the logs of ephemeral containers in a Pod.
+
+If a workload (e.g. "deploy/web") is given, the logs of ephemeral containers in all of its Pods are merged.
+If no Pod is given, the logs of ephemeral containers in all matching Pods (i.e. with --selector or --all-namespaces) are merged.
+When logs are merged from multiple ephemeral containers, each line is prefixed with [namespace/pod/container].
+	`,
+		// Format: "kind/name", "kind name", "pod-name" or none
+		Args: cobra.RangeArgs(0, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, err := k8s.NewClientset(kubeConfig)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			pods, err := getPodsForLogs(client, args)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			targets := k8s.GetLogTargets(pods, ephContainerName)
+			if len(targets) == 0 {
+				ExitError(errors.New("no ephemeral containers found"), 1)
+			}
+
+			logOpts := &corev1.PodLogOptions{
+				Follow: follow,
+			}
+			if since > 0 {
+				// Round up like kubectl so that sub-second durations are not rejected as 0 by the API server
+				seconds := int64(math.Ceil(since.Seconds()))
+				logOpts.SinceSeconds = &seconds
+			}
+
+			if err := client.StreamLogs(kubeConfig.ContextOptions, targets, logOpts, out.GetOutFile(), len(targets) > 1); err != nil {
+				ExitError(err, 1)
+			}
+		},
+	}
+
+	logsCmd.Flags().StringVarP(&ephContainerName, "container", "c", "", ephContainerNameUsage)
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, followUsage)
+	logsCmd.Flags().DurationVarP(&since, "since", "", 0, sinceUsage)
+	logsCmd.Flags().StringVarP(&labelSelector, "selector", "l", "", labelSelectorUsage)
+	logsCmd.Flags().BoolVarP(&allNamespace, "all-namespaces", "A", false, allNamespaceUsage)
+
+	return logsCmd
+}
+
+// Get the pod (or the workload's pods) from arguments if any. Otherwise, list pods with ephemeral containers
+func getPodsForLogs(client *k8s.KubeClientset, args []string) ([]corev1.Pod, error) {
+	if len(args) > 0 {
+		return getPodsFromArgs(client, args)
+	}
+
+	namespace := *kubeConfig.Namespace
+	if allNamespace {
+		namespace = ""
+	}
+
+	return client.ListPods(kubeConfig.ContextOptions, namespace, metav1.ListOptions{LabelSelector: labelSelector, Limit: k8s.DEFAULT_CHUNK_SIZE}, filterFn)
+}
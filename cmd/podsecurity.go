@@ -0,0 +1,155 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	corev1 "k8s.io/api/core/v1"
+	psaapi "k8s.io/pod-security-admission/api"
+)
+
+const (
+	podSecurityPrompt string = "prompt"
+	podSecurityAdjust string = "adjust"
+	podSecurityIgnore string = "ignore"
+)
+
+var (
+	podSecurity      string
+	podSecurityUsage string = fmt.Sprintf("How to handle Pod Security violations of new ephemeral containers found before submitting. One of: %s (offer a compliant variant if any), %s (use a compliant variant without asking), %s (skip the validation)",
+		podSecurityPrompt, podSecurityAdjust, podSecurityIgnore)
+)
+
+// Validate --pod-security
+func validatePodSecurityMode() error {
+	switch podSecurity {
+	case podSecurityPrompt, podSecurityAdjust, podSecurityIgnore:
+		return nil
+	default:
+		return fmt.Errorf("invalid --pod-security %q. One of: %s, %s, %s", podSecurity, podSecurityPrompt, podSecurityAdjust, podSecurityIgnore)
+	}
+}
+
+// Get the Pod Security level and version enforced in the namespace
+// Return nil if the validation is skipped (e.g. the namespace cannot be read) and leave the decision to the API server
+func getPodSecurityEnforcement(client *k8s.KubeClientset, namespace string) *psaapi.LevelVersion {
+	if podSecurity == podSecurityIgnore {
+		return nil
+	}
+
+	lv, err := client.GetPodSecurityEnforcement(kubeConfig.ContextOptions, namespace)
+	if err != nil {
+		out.ErrLn("Warning: skipping Pod Security validation in namespace %s: %s", namespace, err.Error())
+		return nil
+	}
+	return &lv
+}
+
+// Validate the new ephemeral containers in the patch against the Pod Security level enforced in the pod's namespace
+// If violations are found and a compliant variant exists, it is offered (or used directly with --pod-security=adjust)
+// Return the patch to submit
+func checkPodSecurity(client *k8s.KubeClientset, original, patch *corev1.Pod) (*corev1.Pod, error) {
+	lv := getPodSecurityEnforcement(client, original.Namespace)
+	if lv == nil {
+		return patch, nil
+	}
+
+	added := k8s.AddedEphemeralContainers(original, patch)
+	if len(added) == 0 {
+		return patch, nil
+	}
+
+	violations, err := k8s.EvaluatePodSecurity(*lv, original, added)
+	if err != nil || len(violations) == 0 {
+		return patch, err
+	}
+
+	violationErr := podSecurityViolationError(original, *lv, violations)
+	adjusted := patch.DeepCopy()
+	for _, container := range added {
+		compliant, err := k8s.CompliantEphemeralContainer(*lv, original, &container)
+		if err != nil {
+			return nil, errors.Join(violationErr, err)
+		}
+		*k8s.FindEphemeralContainer(adjusted, container.Name) = *compliant
+	}
+
+	// Violations are sent to stderr to keep the output (e.g. dry-run results) parsable
+	out.ErrLn("%s", violationErr.Error())
+	if podSecurity == podSecurityAdjust {
+		out.ErrLn("Ephemeral containers adjusted to comply with Pod Security %q", lv.String())
+		return adjusted, nil
+	}
+
+	diff, err := k8s.UnifiedDiffEphemeralContainers(patch, adjusted)
+	if err != nil {
+		return nil, err
+	}
+	out.Ln("%s", diff)
+
+	confirmed, err := out.Confirm(fmt.Sprintf("Adjust the ephemeral containers to comply with Pod Security %q?", lv.String()))
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("ephemeral containers not adjusted. pod/%s would be rejected by Pod Security %q", original.Name, lv.String())
+	}
+
+	return adjusted, nil
+}
+
+// Validate an ephemeral container added to a pod in bulk mode
+// Bulk mode never prompts. A compliant variant is only used with --pod-security=adjust
+func checkPodSecurityForBulk(lv *psaapi.LevelVersion, pod *corev1.Pod, container *corev1.EphemeralContainer) (*corev1.EphemeralContainer, error) {
+	if lv == nil {
+		return container, nil
+	}
+
+	violations, err := k8s.EvaluatePodSecurity(*lv, pod, []corev1.EphemeralContainer{*container})
+	if err != nil || len(violations) == 0 {
+		return container, err
+	}
+
+	violationErr := podSecurityViolationError(pod, *lv, violations)
+	if podSecurity != podSecurityAdjust {
+		return nil, violationErr
+	}
+
+	compliant, err := k8s.CompliantEphemeralContainer(*lv, pod, container)
+	if err != nil {
+		return nil, errors.Join(violationErr, err)
+	}
+	return compliant, nil
+}
+
+// List the violations in an error, one per line
+func podSecurityViolationError(pod *corev1.Pod, lv psaapi.LevelVersion, violations []k8s.PodSecurityViolation) error {
+	errs := []error{fmt.Errorf("pod/%s would violate Pod Security %q enforced in namespace %s:", pod.Name, lv.String(), pod.Namespace)}
+	for _, violation := range violations {
+		errs = append(errs, fmt.Errorf("  - %s", violation.String()))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,226 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/profile"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	profileNames      []string
+	profileNamesUsage string = fmt.Sprintf("Names of debug profiles defined in the plugin config file or built in (i.e. %s) to expand into the ephemeral container. Can be repeated. Later profiles take precedence",
+		strings.Join(profile.ListBuiltinProfileNames(), ", "))
+
+	customFile      string
+	customFileUsage string = "Path to a JSON or YAML file containing a partial container spec (e.g. env, volumeMounts, securityContext) to strategic-merge on top of the ephemeral container generated from flags and profiles"
+
+	// Parsed from --custom
+	customContainer *corev1.Container
+
+	asTarget      bool
+	asTargetUsage string = "If true, run the ephemeral container with the same user, group and SELinux options as the target container (or the pod)"
+)
+
+func NewProfilesCmd() *cobra.Command {
+	profilesCmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "Command to inspect debug profiles defined in the plugin config file",
+		Long: fmt.Sprintf(`
+Command to inspect debug profiles defined in the plugin config file.
+
+The config file is located at $HOME/.kube/%s. Set environment variable %s to use another location.
+
+The built-in profiles (i.e. %s) have the same security settings as "kubectl debug --profile".
+Profiles in the config file take precedence over built-in profiles with the same name.
+	`, profile.DEFAULT_CONFIG_FILE, profile.ENV_CONFIG, strings.Join(profile.ListBuiltinProfileNames(), ", ")),
+	}
+
+	profilesCmd.AddCommand(newProfilesListCmd(), newProfilesShowCmd())
+
+	return profilesCmd
+}
+
+func newProfilesListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List debug profiles merged with defaults",
+		Long: `
+List debug profiles merged with defaults. Built-in profiles are listed after the ones in the config file
+	`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, path := loadProfileConfig()
+
+			names := config.ListProfileNames()
+			for _, name := range profile.ListBuiltinProfileNames() {
+				if _, found := config.Profiles[name]; !found {
+					names = append(names, name)
+				}
+			}
+
+			profiles := make([]formatter.ProfileData, 0)
+			for _, name := range names {
+				p, err := config.GetProfile(name)
+				if err != nil {
+					ExitError(err, 1)
+				}
+				profiles = append(profiles, formatter.ProfileData{Name: name, Profile: *p})
+			}
+
+			output, err := formatter.FormatProfileListOutput(outputFormat, profiles)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			if len(output) > 0 {
+				out.Ln("%s", output)
+			} else {
+				out.Ln("No profiles found in %s", path)
+			}
+		},
+	}
+}
+
+func newProfilesShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show a debug profile merged with defaults",
+		Long: `
+Show a debug profile merged with defaults
+	`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config, path := loadProfileConfig()
+
+			p, err := config.GetProfile(args[0])
+			if err != nil {
+				ExitError(errors.Join(fmt.Errorf("failed to get profile from %s", path), err), 1)
+			}
+
+			output, err := formatter.FormatProfileOutput(outputFormat, formatter.ProfileData{Name: args[0], Profile: *p})
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			out.Ln("%s", output)
+		},
+	}
+}
+
+// Load the plugin config file and return it with its path
+func loadProfileConfig() (*profile.Config, string) {
+	path, err := profile.GetConfigPath()
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	config, err := profile.LoadConfig(path)
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	return config, path
+}
+
+// Load the partial container spec set with --custom (if any)
+func loadCustomContainer() {
+	if len(customFile) == 0 {
+		return
+	}
+
+	content, err := os.ReadFile(customFile)
+	if err != nil {
+		ExitError(err, 1)
+	}
+
+	if customContainer, err = k8s.ParseCustomContainer(content); err != nil {
+		ExitError(errors.Join(fmt.Errorf("failed to load %s", customFile), err), 1)
+	}
+}
+
+// Resolve the profiles set with --profile (if any) from the plugin config file
+// Return nil if no profiles are set
+func resolveProfiles() (*profile.Profile, error) {
+	if len(profileNames) == 0 {
+		return nil, nil
+	}
+
+	config, path := loadProfileConfig()
+
+	p, err := config.ResolveProfiles(profileNames...)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to get profiles from %s", path), err)
+	}
+
+	return p, nil
+}
+
+// Expand the resolved profiles (if any) into the ephemeral container for the pod,
+// match the identity of the target container if --as-target is set,
+// then merge the partial container spec set with --custom (if any) on top
+// Warnings are reported with warn (e.g. to collect them when adding to pods concurrently)
+func applyProfiles(p *profile.Profile, container *corev1.EphemeralContainer, pod *corev1.Pod, warn func(format string, a ...interface{})) error {
+	if p != nil {
+		if err := p.Apply(container, pod); err != nil {
+			return err
+		}
+	}
+
+	if asTarget {
+		// Same as the target policy "first" if no target is set with flags or profiles
+		if len(container.TargetContainerName) == 0 && len(pod.Spec.Containers) > 0 {
+			container.TargetContainerName = pod.Spec.Containers[0].Name
+		}
+
+		if err := k8s.MatchTargetIdentity(pod, container); err != nil {
+			return err
+		}
+
+		// The user defined in the image (if any) cannot be read from the pod
+		if container.SecurityContext == nil || container.SecurityContext.RunAsUser == nil {
+			warn("Warning: target container %s in pod/%s does not set runAsUser. The ephemeral container runs as the user of its own image", container.TargetContainerName, pod.Name)
+		}
+	}
+
+	if customContainer == nil {
+		return nil
+	}
+
+	merged, err := k8s.MergeCustomContainer(container, customContainer)
+	if err != nil {
+		return err
+	}
+	*container = *merged
+
+	return nil
+}
@@ -38,7 +38,8 @@ var (
 	kubeConfig *k8s.KubeConfig
 
 	outputFormat    string
-	outputFlagUsage string = fmt.Sprintf("Format for output. One of: default (%s for lists), %s, %s", formatter.Table, formatter.JSON, formatter.YAML)
+	outputFlagUsage string = fmt.Sprintf("Format for output. One of: default (%s for lists), %s, %s. For list, also one of: %s, %s, %s=..., %s=..., %s=..., %s=..., %s=...",
+		formatter.Table, formatter.JSON, formatter.YAML, formatter.Wide, formatter.Name, formatter.JSONPath, formatter.JSONPathFile, formatter.GoTemplate, formatter.GoTemplateFile, formatter.CustomColumns)
 )
 
 func NewRootCmd() *cobra.Command {
@@ -77,7 +78,7 @@ func NewRootCmd() *cobra.Command {
 	kubeConfig.AddFlags(rootCmd.PersistentFlags())
 
 	// Add subcommands
-	rootCmd.AddCommand(NewEditCmd(), NewListCmd(), NewVersionCmd())
+	rootCmd.AddCommand(NewAddCmd(), NewApplyCmd(), NewAttachCmd(), NewDescribeCmd(), NewDoctorCmd(), NewEditCmd(), NewExecCmd(), NewListCmd(), NewLogsCmd(), NewProfilesCmd(), NewVersionCmd(), NewWaitCmd())
 
 	return rootCmd
 }
@@ -0,0 +1,112 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var (
+	choosePod      bool
+	choosePodUsage string = "If true and a workload is given, choose one of its Pods interactively. Otherwise, the newest ready Pod is selected"
+)
+
+// Get the pod from arguments
+// A workload (e.g. "deploy/web") is resolved to the newest ready pod or the one chosen with --choose-pod
+func getPodFromArgs(client *k8s.KubeClientset, args []string) (*corev1.Pod, error) {
+	ref, err := k8s.GetResourceRefFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.IsPod() {
+		return client.GetPod(kubeConfig.ContextOptions, *kubeConfig.Namespace, ref.Name)
+	}
+
+	pods, err := client.GetPodsForWorkload(kubeConfig.ContextOptions, *kubeConfig.Namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no pods found for %s", ref)
+	}
+
+	if choosePod {
+		return choosePodInteractively(ref, pods)
+	}
+
+	pod, err := k8s.SelectNewestReadyPod(pods)
+	if err != nil {
+		return nil, err
+	}
+
+	out.ErrLn("Selected pod/%s for %s", pod.Name, ref)
+	return pod, nil
+}
+
+// Get the pods from arguments
+// A workload (e.g. "deploy/web") is resolved to all of its pods
+func getPodsFromArgs(client *k8s.KubeClientset, args []string) ([]corev1.Pod, error) {
+	ref, err := k8s.GetResourceRefFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.IsPod() {
+		pod, err := client.GetPod(kubeConfig.ContextOptions, *kubeConfig.Namespace, ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		return []corev1.Pod{*pod}, nil
+	}
+
+	return client.GetPodsForWorkload(kubeConfig.ContextOptions, *kubeConfig.Namespace, ref)
+}
+
+// Ask to choose one of the workload's pods (newest first)
+func choosePodInteractively(ref *k8s.ResourceRef, pods []corev1.Pod) (*corev1.Pod, error) {
+	sorted := k8s.SortPodsByNewest(pods)
+
+	options := make([]string, len(sorted))
+	for idx, pod := range sorted {
+		ready := "not ready"
+		if k8s.IsPodReady(&pod) {
+			ready = "ready"
+		}
+		options[idx] = fmt.Sprintf("pod/%s (%s, %s, age %s)", pod.Name, pod.Status.Phase, ready, duration.HumanDuration(time.Since(pod.CreationTimestamp.Time)))
+	}
+
+	choice, err := out.Choose(fmt.Sprintf("Select a Pod of %s", ref), options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sorted[choice], nil
+}
@@ -0,0 +1,84 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ephContainerName      string
+	ephContainerNameUsage string = "Name of the ephemeral container"
+
+	waitFor      string
+	waitForUsage string = "Condition to wait for. One of: running, terminated"
+)
+
+func NewWaitCmd() *cobra.Command {
+	waitCmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for an ephemeral container in a Pod to reach a condition",
+		Long: `
+Wait for an ephemeral container in a Pod to reach a condition (i.e. running or terminated).
+
+The command watches the Pod and exits non-zero if the ephemeral container cannot reach the condition, for example, if its image cannot be pulled.
+Use --request-timeout to limit the time to wait.
+	`,
+		// Format: "kind/name", "kind name", "pod-name"
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			condition, err := k8s.ParseContainerCondition(waitFor)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			client, err := k8s.NewClientset(kubeConfig)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			pod, err := getPodFromArgs(client, args)
+			if err != nil {
+				ExitError(err, 1)
+			}
+
+			if _, err = client.WaitForEphemeralContainer(kubeConfig.ContextOptions, *kubeConfig.Namespace, pod.Name, ephContainerName, condition); err != nil {
+				ExitError(err, 1)
+			}
+
+			out.Ln("ephemeral container %s in pod/%s is %s", ephContainerName, pod.Name, condition)
+		},
+	}
+
+	waitCmd.Flags().StringVarP(&ephContainerName, "container", "c", "", ephContainerNameUsage)
+	waitCmd.Flags().StringVarP(&waitFor, "for", "", string(k8s.ConditionRunning), waitForUsage)
+	waitCmd.Flags().BoolVarP(&choosePod, "choose-pod", "", false, choosePodUsage)
+
+	if err := waitCmd.MarkFlagRequired("container"); err != nil {
+		ExitError(err, 1)
+	}
+
+	return waitCmd
+}
@@ -26,8 +26,10 @@ import (
 	"fmt"
 
 	"github.com/k8s-crafts/ephemeral-containers-plugin/e2e/testutils"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
 )
 
 var _ = Describe("kubectl ephemeral-containers", func() {
@@ -79,7 +81,23 @@ var _ = Describe("kubectl ephemeral-containers", func() {
 				DescribeTable("should list in expected format", func(format string) {
 					actual, err := tr.RunPluginListCmd(format, namespace)
 					Expect(err).ToNot(HaveOccurred())
-					Expect(actual).To(Equal(tr.NewListOutput(format, namespace)))
+
+					if len(format) == 0 {
+						Expect(actual).To(Equal(tr.NewListOutput(namespace)))
+						return
+					}
+
+					// YAML is a superset of JSON
+					data := []formatter.ResourceData{}
+					Expect(yaml.Unmarshal([]byte(actual), &data)).To(Succeed())
+
+					// Ignore fields that depend on the cluster
+					for idx := range data {
+						data[idx].NodeName = ""
+						data[idx].Running, data[idx].Terminated = 0, 0
+						data[idx].NewestStartedAt = nil
+					}
+					Expect(data).To(Equal(tr.NewListData(namespace)))
 				},
 					Entry("in Table", ""),
 					Entry("in JSON", "json"),
@@ -112,9 +130,18 @@ var _ = Describe("kubectl ephemeral-containers", func() {
 				Expect(actual).To(ContainSubstring("Global Flags:"))
 			}
 		},
+			Entry("add", "add"),
+			Entry("apply", "apply"),
+			Entry("attach", "attach"),
+			Entry("describe", "describe"),
+			Entry("doctor", "doctor"),
 			Entry("list", "list"),
+			Entry("logs", "logs"),
 			Entry("edit", "edit"),
+			Entry("exec", "exec"),
+			Entry("profiles", "profiles"),
 			Entry("version", "version"),
+			Entry("wait", "wait"),
 			Entry("root", ""),
 		)
 	})
@@ -166,7 +193,7 @@ var _ = Describe("kubectl ephemeral-containers", func() {
 					actual, err := tr.RunPluginEditCmd(tr.Kubectl.Namespace, testutils.TestPodName)
 
 					Expect(err).To(HaveOccurred())
-					Expect(actual).To(ContainSubstring(fmt.Sprintf("Pod \"%s\" is invalid: spec.ephemeralContainers: Forbidden: existing ephemeral containers \"%s\" may not be changed", testutils.TestPodName, testutils.EphContainerName)))
+					Expect(actual).To(ContainSubstring(fmt.Sprintf("spec.ephemeralContainers[%s].image: Forbidden: existing ephemeral container may not be changed", testutils.EphContainerName)))
 				})
 			})
 
@@ -179,7 +206,7 @@ var _ = Describe("kubectl ephemeral-containers", func() {
 					actual, err := tr.RunPluginEditCmd(tr.Kubectl.Namespace, testutils.TestPodName)
 
 					Expect(err).To(HaveOccurred())
-					Expect(actual).To(ContainSubstring(fmt.Sprintf("Pod \"%s\" is invalid: spec.ephemeralContainers: Forbidden: existing ephemeral containers \"%s\" may not be removed", testutils.TestPodName, testutils.EphContainerName)))
+					Expect(actual).To(ContainSubstring(fmt.Sprintf("spec.ephemeralContainers[%s]: Forbidden: existing ephemeral container may not be removed", testutils.EphContainerName)))
 				})
 			})
 
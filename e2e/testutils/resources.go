@@ -24,6 +24,8 @@ package testutils
 
 import (
 	"fmt"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
 )
 
 var (
@@ -33,46 +35,9 @@ var (
 	EphContainerName string = "debugger"
 )
 
-func (t *TestResource) NewListOutput(format string, namespace string) string {
+// Expected list output in table format
+func (t *TestResource) NewListOutput(namespace string) string {
 	if len(namespace) == 0 {
-		return t.newListInAllNamespaces(format)
-	}
-	return t.newListInNamespace(format, namespace)
-}
-
-func (t *TestResource) newListInAllNamespaces(format string) string {
-	switch format {
-	case "json":
-		return fmt.Sprintf(`[
-  {
-    "name": "%s",
-    "namespace": "%s",
-    "ephemeralContainers": [
-      "%s"
-    ]
-  },
-  {
-    "name": "%s",
-    "namespace": "%s",
-    "ephemeralContainers": [
-      "%s"
-    ]
-  }
-]
-`, TestPodName, t.Namespace, EphContainerName, TestPodName, t.AnotherNamespace, EphContainerName,
-		)
-	case "yaml":
-		return fmt.Sprintf(`- ephemeralContainers:
-  - %s
-  name: %s
-  namespace: %s
-- ephemeralContainers:
-  - %s
-  name: %s
-  namespace: %s
-
-`, EphContainerName, TestPodName, t.Namespace, EphContainerName, TestPodName, t.AnotherNamespace)
-	default: // table or empty
 		return fmt.Sprintf(
 			`+------------+-----------+----------------------+
 |    POD     | NAMESPACE | EPHEMERAL CONTAINERS |
@@ -83,39 +48,36 @@ func (t *TestResource) newListInAllNamespaces(format string) string {
 
 `, TestPodName, t.Namespace, EphContainerName, TestPodName, t.AnotherNamespace, EphContainerName)
 	}
-}
-
-func (t *TestResource) newListInNamespace(format string, namespace string) string {
-	switch format {
-	case "json":
-		return fmt.Sprintf(`[
-  {
-    "name": "%s",
-    "namespace": "%s",
-    "ephemeralContainers": [
-      "%s"
-    ]
-  }
-]
-`, TestPodName, namespace, EphContainerName,
-		)
-	case "yaml":
-		return fmt.Sprintf(`- ephemeralContainers:
-  - %s
-  name: %s
-  namespace: %s
 
-`, EphContainerName, TestPodName, t.Namespace)
-	default: // table or empty
-		return fmt.Sprintf(
-			`+------------+-----------+----------------------+
+	return fmt.Sprintf(
+		`+------------+-----------+----------------------+
 |    POD     | NAMESPACE | EPHEMERAL CONTAINERS |
 +------------+-----------+----------------------+
 | %s | %s | %s             |
 +------------+-----------+----------------------+
 
 `, TestPodName, namespace, EphContainerName)
+}
+
+// Expected list data in JSON and YAML format
+// Fields that depend on the cluster (i.e. node name, states and start times) are not included
+func (t *TestResource) NewListData(namespace string) []formatter.ResourceData {
+	namespaces := []string{namespace}
+	if len(namespace) == 0 {
+		namespaces = t.GetTestNamespaces()
+	}
+
+	data := make([]formatter.ResourceData, 0, len(namespaces))
+	for _, ns := range namespaces {
+		data = append(data, formatter.ResourceData{
+			Name:                TestPodName,
+			Namespace:           ns,
+			EphemeralContainers: []string{EphContainerName},
+			Count:               1,
+			Images:              []string{DebugImage},
+		})
 	}
+	return data
 }
 
 func (t *TestResource) NewListEmptyMessage(namespace string) string {
@@ -162,7 +162,8 @@ func (t *TestResource) RunPluginListCmd(format string, namespace string) (string
 
 func (t *TestResource) RunPluginEditCmd(namespace string, podName string) (string, error) {
 	// Setting namespace manually as flags cannot be set before plugin name
-	return t.Kubectl.Command(t.PluginName, "edit", "-n", namespace, podName)
+	// Skip the confirmation prompt as there is no interactive stdin
+	return t.Kubectl.Command(t.PluginName, "edit", "--yes", "-n", namespace, podName)
 }
 
 func (t *TestResource) WaitForTestPodReady(namespace string) error {
@@ -63,6 +63,7 @@ func main() {
 	// Initialize the out and err destinations
 	out.SetOutFile(os.Stdout)
 	out.SetErrFile(os.Stderr)
+	out.SetInFile(os.Stdin)
 
 	// Execute the command
 	cmd.Execute()
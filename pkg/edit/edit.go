@@ -23,10 +23,13 @@
 package edit
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/yaml"
@@ -39,40 +42,119 @@ const (
 	TMP_FILE_PATTERN string = "ephemeral-containers-*.yaml"
 )
 
-// Edit a k8s resource and return the updated one
-func EditResource[r runtime.Object](ctx context.Context, editor string, obj r, result r) (r, error) {
+const editHeader string = `# Please edit the object below. Lines beginning with a '#' will be ignored,
+# and an empty file will abort the edit. If an error occurs while saving this file will be
+# reopened with the relevant failures.
+#
+`
+
+// Returned when the user saves an empty file
+var ErrEditCancelled = errors.New("edit cancelled, saved file was empty")
+
+// Callback to process an edited k8s resource
+// If an error is returned, the editor is reopened with the error
+type ApplyFn[r runtime.Object] func(edited r) error
+
+// Edit a k8s resource and pass the updated one to the apply callback
+// The result is used as a template to decode the edited content for each attempt
+// The editor is reopened with errors from decoding or the callback until the user:
+// * saves an empty file: ErrEditCancelled is returned
+// * saves the file unchanged after an error: the file is kept and its path is reported
+// Once the content has been edited, the file is also kept on any other error (e.g. the editor exits non-zero or is interrupted)
+func EditResource[r runtime.Object](ctx context.Context, editor string, obj r, result r, apply ApplyFn[r]) (err error) {
 	f, err := os.CreateTemp(os.TempDir(), TMP_FILE_PATTERN)
 	if err != nil {
-		return result, err
+		return err
 	}
+
+	keep := false
 	defer func() {
-		// Clean up
-		err = errors.Join(err, f.Close(), os.Remove(f.Name()))
+		// Clean up unless the buffer is kept for recovery
+		err = errors.Join(err, f.Close())
+		if !keep {
+			err = errors.Join(err, os.Remove(f.Name()))
+		}
 	}()
 
 	content, err := yaml.Marshal(obj)
 	if err != nil {
-		return result, err
+		return err
 	}
 
-	if _, err = f.Write(content); err != nil {
-		return result, err
+	// Keep the buffer for recovery and report its path
+	// Only after the first edit. Before that, the buffer only has the original content
+	var lastErr error
+	recoverable := func(err error) error {
+		if lastErr == nil {
+			return err
+		}
+		keep = true
+		return errors.Join(fmt.Errorf("edit cancelled, the edited content has been saved to %s", f.Name()), err)
 	}
 
-	if err = OpenEditorForFile(ctx, editor, f.Name()); err != nil {
-		return result, err
+	for {
+		if err = writeBuffer(f.Name(), content, lastErr); err != nil {
+			return recoverable(err)
+		}
+
+		if err = OpenEditorForFile(ctx, editor, f.Name()); err != nil {
+			return recoverable(err)
+		}
+
+		var edited []byte
+		if edited, err = os.ReadFile(f.Name()); err != nil {
+			return recoverable(err)
+		}
+		edited = stripHeader(edited)
+
+		if len(bytes.TrimSpace(edited)) == 0 {
+			return ErrEditCancelled
+		}
+
+		if lastErr != nil && bytes.Equal(edited, content) {
+			return recoverable(lastErr)
+		}
+		content = edited
+
+		editedObj := result.DeepCopyObject().(r)
+		if lastErr = yaml.Unmarshal(content, editedObj); lastErr != nil {
+			continue
+		}
+
+		if lastErr = apply(editedObj); lastErr == nil {
+			return nil
+		}
 	}
+}
+
+// Write the content to the buffer file with instructions and an error (if any) as header comments
+func writeBuffer(path string, content []byte, err error) error {
+	var buffer bytes.Buffer
+	buffer.WriteString(editHeader)
 
-	content, err = os.ReadFile(f.Name())
 	if err != nil {
-		return result, err
+		for _, line := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+			fmt.Fprintf(&buffer, "# %s\n", line)
+		}
+		buffer.WriteString("#\n")
 	}
 
-	err = yaml.Unmarshal(content, result)
-	if err != nil {
-		return result, err
+	buffer.Write(content)
+
+	return os.WriteFile(path, buffer.Bytes(), 0600)
+}
+
+// Remove the header comments (i.e. leading lines beginning with '#') from the content
+func stripHeader(content []byte) []byte {
+	for len(content) > 0 {
+		line, rest, _ := bytes.Cut(content, []byte("\n"))
+		if !bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			break
+		}
+		content = rest
 	}
-	return result, nil
+
+	return content
 }
 
 // Execute editor command for a file path and await closing editor
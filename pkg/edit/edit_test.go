@@ -23,11 +23,18 @@
 package edit_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/edit"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("Edit", func() {
@@ -83,6 +90,173 @@ var _ = Describe("Edit", func() {
 	})
 })
 
+var _ = Describe("EditResource", func() {
+	var t *editTest
+
+	BeforeEach(func() {
+		t = newEditTest()
+	})
+
+	Context("with a valid edit", func() {
+		BeforeEach(func() {
+			t.writeEditor(`sed -i 's/value/edited/' "$1"`)
+		})
+
+		It("should apply the edited resource", func() {
+			Expect(t.edit()).To(Succeed())
+			Expect(t.applied).To(HaveLen(1))
+			Expect(t.applied[0].Data).To(HaveKeyWithValue("key", "edited"))
+		})
+
+		It("should write instructions as header", func() {
+			Expect(t.edit()).To(Succeed())
+			Expect(t.buffer(1)).To(HavePrefix("# Please edit the object below."))
+		})
+	})
+
+	Context("with an empty file", func() {
+		BeforeEach(func() {
+			t.writeEditor(`: > "$1"`)
+		})
+
+		It("should cancel the edit", func() {
+			Expect(t.edit()).To(MatchError(edit.ErrEditCancelled))
+			Expect(t.applied).To(BeEmpty())
+		})
+	})
+
+	Context("with a parse error", func() {
+		BeforeEach(func() {
+			t.writeEditor(`echo 'data: [' >> "$1"`, `sed -i '$d' "$1"`)
+		})
+
+		It("should reopen the editor with the error", func() {
+			Expect(t.edit()).To(Succeed())
+			Expect(t.buffer(2)).To(ContainSubstring("# error converting YAML to JSON"))
+			Expect(t.buffer(2)).To(ContainSubstring("data: [\n"))
+			Expect(t.applied).To(HaveLen(1))
+		})
+	})
+
+	Context("with an error from apply", func() {
+		BeforeEach(func() {
+			t.applyErrs = []error{errors.New("invalid value")}
+			t.writeEditor(`sed -i 's/value/edited/' "$1"`, `sed -i 's/edited/fixed/' "$1"`)
+		})
+
+		It("should reopen the editor with the error", func() {
+			Expect(t.edit()).To(Succeed())
+			Expect(t.buffer(2)).To(ContainSubstring("# invalid value\n"))
+			Expect(t.applied).To(HaveLen(2))
+			Expect(t.applied[1].Data).To(HaveKeyWithValue("key", "fixed"))
+		})
+	})
+
+	Context("with an unchanged file after an error", func() {
+		BeforeEach(func() {
+			t.applyErrs = []error{errors.New("invalid value")}
+			t.writeEditor(`sed -i 's/value/edited/' "$1"`, `:`)
+		})
+
+		It("should keep the file for recovery", func() {
+			err := t.edit()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid value"))
+
+			saved := strings.TrimSpace(strings.Split(strings.Split(err.Error(), "saved to ")[1], "\n")[0])
+			DeferCleanup(os.Remove, saved)
+
+			content, err := os.ReadFile(saved)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("key: edited"))
+		})
+	})
+
+	Context("with the editor exiting non-zero after an error", func() {
+		BeforeEach(func() {
+			t.applyErrs = []error{errors.New("invalid value")}
+			t.writeEditor(`sed -i 's/value/edited/' "$1"`)
+		})
+
+		It("should keep the file for recovery", func() {
+			err := t.edit()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exit status 1"))
+
+			saved := strings.TrimSpace(strings.Split(strings.Split(err.Error(), "saved to ")[1], "\n")[0])
+			DeferCleanup(os.Remove, saved)
+
+			content, err := os.ReadFile(saved)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("key: edited"))
+		})
+	})
+
+	Context("with the editor exiting non-zero before any edit", func() {
+		BeforeEach(func() {
+			t.writeEditor()
+		})
+
+		It("should not keep the file", func() {
+			err := t.edit()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).ToNot(ContainSubstring("saved to"))
+		})
+	})
+})
+
+type editTest struct {
+	dir       string
+	editor    string
+	applied   []*corev1.ConfigMap
+	applyErrs []error
+}
+
+func newEditTest() *editTest {
+	return &editTest{
+		dir: GinkgoT().TempDir(),
+	}
+}
+
+// Write an editor script that runs the given step for each invocation
+// The buffer of each invocation is copied to the test directory
+func (t *editTest) writeEditor(steps ...string) {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&script, "n=$(( $(cat %[1]s/count 2>/dev/null || echo 0) + 1 )); echo $n > %[1]s/count\n", t.dir)
+	fmt.Fprintf(&script, "cp \"$1\" %s/buffer-$n.yaml\n", t.dir)
+	script.WriteString("case $n in\n")
+	for i, step := range steps {
+		fmt.Fprintf(&script, "%d) %s ;;\n", i+1, step)
+	}
+	script.WriteString("*) exit 1 ;;\nesac\n")
+
+	t.editor = path.Join(t.dir, "editor.sh")
+	Expect(os.WriteFile(t.editor, []byte(script.String()), 0700)).To(Succeed())
+}
+
+func (t *editTest) edit() error {
+	obj := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-config"},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	return edit.EditResource(context.TODO(), t.editor, obj, &corev1.ConfigMap{}, func(edited *corev1.ConfigMap) error {
+		t.applied = append(t.applied, edited)
+		if len(t.applyErrs) >= len(t.applied) {
+			return t.applyErrs[len(t.applied)-1]
+		}
+		return nil
+	})
+}
+
+// Get the buffer opened in the n-th editor invocation
+func (t *editTest) buffer(n int) string {
+	content, err := os.ReadFile(path.Join(t.dir, fmt.Sprintf("buffer-%d.yaml", n)))
+	Expect(err).ToNot(HaveOccurred())
+	return string(content)
+}
+
 // Struct type representing an environment variable
 // as a key-value pair
 type env struct {
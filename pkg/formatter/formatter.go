@@ -26,11 +26,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/profile"
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/version"
 	"github.com/olekukonko/tablewriter"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
 	"sigs.k8s.io/yaml"
 )
 
@@ -38,16 +44,112 @@ const (
 	JSON  string = "json"
 	YAML  string = "yaml"
 	Table string = "table" // Default format
+	Wide  string = "wide"  // Table with additional columns (list only)
+)
+
+const (
+	// States of ephemeral containers
+	StateWaiting    string = "Waiting"
+	StateRunning    string = "Running"
+	StateTerminated string = "Terminated"
+	StatePending    string = "Pending" // In spec but no status reported yet
+	StateUnknown    string = "Unknown"
+)
+
+const (
+	// ANSI escape codes for colored output
+	colorReset string = "\033[0m"
+	colorBold  string = "\033[1m"
+	colorRed   string = "\033[31m"
+	colorGreen string = "\033[32m"
+	colorCyan  string = "\033[36m"
 )
 
 var (
-	TableHeaders []string = []string{"Pod", "Namespace", "Ephemeral Containers"}
+	TableHeaders         []string = []string{"Pod", "Namespace", "Ephemeral Containers"}
+	WideTableHeaders     []string = []string{"Pod", "Namespace", "Ephemeral Containers", "Node", "Count", "Images", "Targets", "Running", "Terminated", "Age"}
+	DescribeTableHeaders []string = []string{"Container", "Image", "Target", "Command", "State", "Reason", "Exit Code", "Started", "Finished"}
+	ProfileTableHeaders  []string = []string{"Profile", "Image", "Command", "Target Policy"}
+	WatchTableHeaders    []string = []string{"Pod", "Namespace", "Ephemeral Containers", "Event"}
+	BulkTableHeaders     []string = []string{"Pod", "Namespace", "Container", "Result", "Message"}
+	DoctorTableHeaders   []string = []string{"Check", "Status", "Message", "Hint"}
+
+	PerContainerTableHeaders []string = []string{"Namespace", "Pod", "Container", "Image", "Target", "State", "Started", "Exit Code"}
 )
 
 type ResourceData struct {
-	Name                string   `json:"name,omitempty"`
-	Namespace           string   `json:"namespace,omitempty"`
-	EphemeralContainers []string `json:"ephemeralContainers"`
+	Name                string       `json:"name,omitempty"`
+	Namespace           string       `json:"namespace,omitempty"`
+	EphemeralContainers []string     `json:"ephemeralContainers"`
+	NodeName            string       `json:"nodeName,omitempty"`
+	Count               int          `json:"count"`
+	Images              []string     `json:"images,omitempty"`  // Distinct images
+	Targets             []string     `json:"targets,omitempty"` // Distinct target containers
+	Running             int          `json:"running"`
+	Terminated          int          `json:"terminated"`
+	NewestStartedAt     *metav1.Time `json:"newestStartedAt,omitempty"` // Start time of the most recently started ephemeral container
+}
+
+// Represent an ephemeral container in a flattened list (i.e. one row per ephemeral container)
+type ContainerRowData struct {
+	Namespace string       `json:"namespace"`
+	Pod       string       `json:"pod"`
+	Container string       `json:"container"`
+	Image     string       `json:"image"`
+	Target    string       `json:"target,omitempty"`
+	State     string       `json:"state"`
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+	ExitCode  *int32       `json:"exitCode,omitempty"`
+}
+
+// Represent a change to a pod with ephemeral containers in watch mode
+type WatchEventData struct {
+	Type   string       `json:"type"`
+	Object ResourceData `json:"object"`
+}
+
+// Represent the result of adding an ephemeral container to a pod in bulk
+type BulkResultData struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Container string `json:"container,omitempty"`
+	Result    string `json:"result"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Represent the result of a preflight check
+type DoctorCheckData struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Represent the spec and state of an ephemeral container
+type EphemeralContainerData struct {
+	Name       string       `json:"name"`
+	Image      string       `json:"image"`
+	Target     string       `json:"target,omitempty"`
+	Command    []string     `json:"command,omitempty"`
+	State      string       `json:"state"`
+	Reason     string       `json:"reason,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	ExitCode   *int32       `json:"exitCode,omitempty"`
+	StartedAt  *metav1.Time `json:"startedAt,omitempty"`
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+}
+
+// Represent a Pod with details of its ephemeral containers
+type DescribeData struct {
+	Name                string                   `json:"name"`
+	Namespace           string                   `json:"namespace"`
+	EphemeralContainers []EphemeralContainerData `json:"ephemeralContainers"`
+}
+
+// Represent a named debug profile
+type ProfileData struct {
+	Name string `json:"name"`
+	profile.Profile
 }
 
 // List the name of ehemeral containers for a Pod
@@ -61,22 +163,261 @@ func ListEphemeralContainersForPod(pod corev1.Pod) (containers []string) {
 // Convert Pod data to simplified version
 func ConvertPodsToResourceData(pods []corev1.Pod) (data []ResourceData) {
 	for _, pod := range pods {
-		data = append(data, ResourceData{
+		d := ResourceData{
 			Name:                pod.Name,
 			Namespace:           pod.Namespace,
 			EphemeralContainers: ListEphemeralContainersForPod(pod),
-		})
+			NodeName:            pod.Spec.NodeName,
+			Count:               len(pod.Spec.EphemeralContainers),
+		}
+
+		for _, container := range GetEphemeralContainersData(pod) {
+			d.Images = appendDistinct(d.Images, container.Image)
+			d.Targets = appendDistinct(d.Targets, container.Target)
+
+			switch container.State {
+			case StateRunning:
+				d.Running++
+			case StateTerminated:
+				d.Terminated++
+			}
+
+			if container.StartedAt != nil && (d.NewestStartedAt == nil || d.NewestStartedAt.Before(container.StartedAt)) {
+				d.NewestStartedAt = container.StartedAt
+			}
+		}
+
+		data = append(data, d)
 	}
 	return data
 }
 
+// Append a non-empty value to a list if not present
+func appendDistinct(values []string, value string) []string {
+	if len(value) == 0 || slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// Get the spec and state of ephemeral containers for a Pod
+// Containers without a status (i.e. not yet processed by the kubelet) are in state Pending
+func GetEphemeralContainersData(pod corev1.Pod) []EphemeralContainerData {
+	statuses := make(map[string]corev1.ContainerStatus)
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		statuses[status.Name] = status
+	}
+
+	data := make([]EphemeralContainerData, 0)
+	for _, container := range pod.Spec.EphemeralContainers {
+		d := EphemeralContainerData{
+			Name:    container.Name,
+			Image:   container.Image,
+			Target:  container.TargetContainerName,
+			Command: append(append([]string{}, container.Command...), container.Args...),
+			State:   StatePending,
+		}
+
+		if status, ok := statuses[container.Name]; ok {
+			setContainerState(&d, status.State)
+		}
+
+		data = append(data, d)
+	}
+	return data
+}
+
+// Set the state of ephemeral container data from a container state
+func setContainerState(data *EphemeralContainerData, state corev1.ContainerState) {
+	switch {
+	case state.Waiting != nil:
+		data.State = StateWaiting
+		data.Reason = state.Waiting.Reason
+		data.Message = state.Waiting.Message
+	case state.Running != nil:
+		data.State = StateRunning
+		data.StartedAt = state.Running.StartedAt.DeepCopy()
+	case state.Terminated != nil:
+		data.State = StateTerminated
+		data.Reason = state.Terminated.Reason
+		data.Message = state.Terminated.Message
+		exitCode := state.Terminated.ExitCode
+		data.ExitCode = &exitCode
+		data.StartedAt = state.Terminated.StartedAt.DeepCopy()
+		data.FinishedAt = state.Terminated.FinishedAt.DeepCopy()
+	default:
+		data.State = StateUnknown
+	}
+}
+
 // Get a table row from resource data
 func GetTableRow(data ResourceData) []string {
 	return []string{data.Name, data.Namespace, strings.Join(data.EphemeralContainers, ",")}
 }
 
+// Get a table row with additional columns from resource data
+func GetWideTableRow(data ResourceData) []string {
+	age := ""
+	if data.NewestStartedAt != nil {
+		age = duration.HumanDuration(time.Since(data.NewestStartedAt.Time))
+	}
+
+	return append(GetTableRow(data),
+		data.NodeName,
+		strconv.Itoa(data.Count),
+		strings.Join(data.Images, ","),
+		strings.Join(data.Targets, ","),
+		strconv.Itoa(data.Running),
+		strconv.Itoa(data.Terminated),
+		age,
+	)
+}
+
+// Get ephemeral containers of a pod with their states (e.g. "debugger (Running)")
+func GetEphemeralContainerStates(pod corev1.Pod) string {
+	containers := make([]string, 0)
+	for _, d := range GetEphemeralContainersData(pod) {
+		containers = append(containers, fmt.Sprintf("%s (%s)", d.Name, d.State))
+	}
+	return strings.Join(containers, ",")
+}
+
+// Get a table row from a pod event in watch mode
+func GetWatchTableRow(eventType string, pod corev1.Pod) []string {
+	return []string{pod.Name, pod.Namespace, GetEphemeralContainerStates(pod), eventType}
+}
+
+// Formatter for watch events. Each pod is an event of the same type
+// In table format, the header is only printed if requested (i.e. for the initial list)
+// In JSON and YAML format, each event is printed as a separate document
+// With kubectl-compatible printers (e.g. jsonpath), each pod is printed separately
+func FormatWatchEventOutput(format string, eventType string, pods []corev1.Pod, header bool) (string, error) {
+	if err := ValidateListFormat(format); err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+
+	switch format {
+	case JSON:
+		for _, d := range ConvertPodsToResourceData(pods) {
+			jsonOut, err := json.Marshal(WatchEventData{Type: eventType, Object: d})
+			if err != nil {
+				return "", err
+			}
+			buffer.Write(jsonOut)
+			buffer.WriteString("\n")
+		}
+	case YAML:
+		for _, d := range ConvertPodsToResourceData(pods) {
+			yamlOut, err := yaml.Marshal(WatchEventData{Type: eventType, Object: d})
+			if err != nil {
+				return "", err
+			}
+			buffer.WriteString("---\n")
+			buffer.Write(yamlOut)
+		}
+	case Table, Wide, "":
+		table := tablewriter.NewWriter(&buffer)
+		table.SetAutoWrapText(false)
+		if header {
+			table.SetHeader(WatchTableHeaders)
+		} else {
+			table.SetBorders(tablewriter.Border{Left: true, Right: true})
+		}
+
+		for _, pod := range pods {
+			table.Append(GetWatchTableRow(eventType, pod))
+		}
+
+		table.Render()
+	default:
+		for _, pod := range pods {
+			pod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+			output, err := FormatWithPrinter(format, &pod)
+			if err != nil {
+				return "", err
+			}
+			buffer.WriteString(output + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// Convert pods to one row per ephemeral container, built from the spec and the status of ephemeral containers
+func ConvertPodsToContainerRowData(pods []corev1.Pod) (data []ContainerRowData) {
+	for _, pod := range pods {
+		for _, container := range GetEphemeralContainersData(pod) {
+			data = append(data, ContainerRowData{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Container: container.Name,
+				Image:     container.Image,
+				Target:    container.Target,
+				State:     container.State,
+				StartedAt: container.StartedAt,
+				ExitCode:  container.ExitCode,
+			})
+		}
+	}
+	return data
+}
+
+// Get a table row from a flattened ephemeral container
+func GetPerContainerTableRow(data ContainerRowData) []string {
+	exitCode := ""
+	if data.ExitCode != nil {
+		exitCode = fmt.Sprintf("%d", *data.ExitCode)
+	}
+
+	return []string{data.Namespace, data.Pod, data.Container, data.Image, data.Target, data.State, formatTime(data.StartedAt), exitCode}
+}
+
+// Formatter for list output with one row per ephemeral container
+// kubectl-compatible printers (except name) are evaluated against a v1 List of the rows (e.g. "jsonpath={.items[*].container}")
+func FormatPerContainerListOutput(format string, pods []corev1.Pod) (string, error) {
+	if err := ValidateListFormat(format); err != nil {
+		return "", err
+	}
+
+	data := ConvertPodsToContainerRowData(pods)
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(data, "", "  ")
+		return string(jsonOut), err
+	case YAML:
+		yamlOut, err := yaml.Marshal(data)
+		return string(yamlOut), err
+	case Table, Wide, "":
+		var buffer bytes.Buffer
+		table := tablewriter.NewWriter(&buffer)
+
+		// Add header
+		table.SetHeader(PerContainerTableHeaders)
+
+		for _, d := range data {
+			table.Append(GetPerContainerTableRow(d))
+		}
+
+		table.Render()
+
+		return buffer.String(), nil
+	default:
+		return FormatDataWithPrinter(format, data)
+	}
+}
+
 // Formatter for list output
 func FormatListOutput(format string, pods []corev1.Pod) (string, error) {
+	if err := ValidateListFormat(format); err != nil {
+		return "", err
+	}
+
 	data := ConvertPodsToResourceData(pods)
 	if len(data) == 0 {
 		return "", nil
@@ -89,7 +430,21 @@ func FormatListOutput(format string, pods []corev1.Pod) (string, error) {
 	case YAML:
 		yamlOut, err := yaml.Marshal(data)
 		return string(yamlOut), err
-	default:
+	case Wide:
+		var buffer bytes.Buffer
+		table := tablewriter.NewWriter(&buffer)
+
+		// Add header
+		table.SetHeader(WideTableHeaders)
+
+		for _, d := range data {
+			table.Append(GetWideTableRow(d))
+		}
+
+		table.Render()
+
+		return buffer.String(), nil
+	case Table, "":
 		var buffer bytes.Buffer
 		table := tablewriter.NewWriter(&buffer)
 
@@ -103,7 +458,236 @@ func FormatListOutput(format string, pods []corev1.Pod) (string, error) {
 		table.Render()
 
 		return buffer.String(), nil
+	default:
+		return FormatPodsWithPrinter(format, pods)
+	}
+}
+
+// Get a table row from ephemeral container data
+func GetDescribeTableRow(data EphemeralContainerData) []string {
+	exitCode := ""
+	if data.ExitCode != nil {
+		exitCode = fmt.Sprintf("%d", *data.ExitCode)
+	}
+
+	return []string{
+		data.Name,
+		data.Image,
+		data.Target,
+		strings.Join(data.Command, " "),
+		data.State,
+		data.Reason,
+		exitCode,
+		formatTime(data.StartedAt),
+		formatTime(data.FinishedAt),
+	}
+}
+
+// Format a timestamp as RFC3339. Empty if unset
+func formatTime(t *metav1.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Formatter for describe output
+func FormatDescribeOutput(format string, pod corev1.Pod) (string, error) {
+	data := DescribeData{
+		Name:                pod.Name,
+		Namespace:           pod.Namespace,
+		EphemeralContainers: GetEphemeralContainersData(pod),
+	}
+
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(data, "", "  ")
+		return string(jsonOut), err
+	case YAML:
+		yamlOut, err := yaml.Marshal(data)
+		return string(yamlOut), err
+	default:
+		var buffer bytes.Buffer
+		fmt.Fprintf(&buffer, "Pod: %s\nNamespace: %s\n", data.Name, data.Namespace)
+
+		if len(data.EphemeralContainers) == 0 {
+			buffer.WriteString("Ephemeral Containers: <none>\n")
+			return buffer.String(), nil
+		}
+
+		table := tablewriter.NewWriter(&buffer)
+		table.SetHeader(DescribeTableHeaders)
+		table.SetAutoWrapText(false)
+
+		for _, d := range data.EphemeralContainers {
+			table.Append(GetDescribeTableRow(d))
+		}
+
+		table.Render()
+
+		return buffer.String(), nil
+	}
+}
+
+// Get a table row from profile data
+func GetProfileTableRow(data ProfileData) []string {
+	targetPolicy := string(data.TargetPolicy)
+	if len(targetPolicy) == 0 {
+		targetPolicy = string(profile.TargetPolicyNone)
+	} else if data.TargetPolicy == profile.TargetPolicyNamed {
+		targetPolicy = fmt.Sprintf("%s (%s)", targetPolicy, data.Target)
+	}
+
+	return []string{data.Name, data.Image, strings.Join(append(append([]string{}, data.Command...), data.Args...), " "), targetPolicy}
+}
+
+// Formatter for profile list output
+func FormatProfileListOutput(format string, profiles []ProfileData) (string, error) {
+	if len(profiles) == 0 {
+		return "", nil
+	}
+
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(profiles, "", "  ")
+		return string(jsonOut), err
+	case YAML:
+		yamlOut, err := yaml.Marshal(profiles)
+		return string(yamlOut), err
+	default:
+		var buffer bytes.Buffer
+		table := tablewriter.NewWriter(&buffer)
+		table.SetHeader(ProfileTableHeaders)
+		table.SetAutoWrapText(false)
+
+		for _, d := range profiles {
+			table.Append(GetProfileTableRow(d))
+		}
+
+		table.Render()
+
+		return buffer.String(), nil
+	}
+}
+
+// Formatter for a single profile. Default to YAML
+func FormatProfileOutput(format string, data ProfileData) (string, error) {
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(data, "", "  ")
+		return string(jsonOut), err
+	default:
+		yamlOut, err := yaml.Marshal(data)
+		return string(yamlOut), err
+	}
+}
+
+// Get a table row from bulk result data
+func GetBulkTableRow(data BulkResultData) []string {
+	return []string{data.Name, data.Namespace, data.Container, data.Result, data.Message}
+}
+
+// Formatter for bulk results
+func FormatBulkResultOutput(format string, results []BulkResultData) (string, error) {
+	if len(results) == 0 {
+		return "", nil
+	}
+
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(results, "", "  ")
+		return string(jsonOut), err
+	case YAML:
+		yamlOut, err := yaml.Marshal(results)
+		return string(yamlOut), err
+	default:
+		var buffer bytes.Buffer
+		table := tablewriter.NewWriter(&buffer)
+		table.SetHeader(BulkTableHeaders)
+		table.SetAutoWrapText(false)
+
+		for _, d := range results {
+			table.Append(GetBulkTableRow(d))
+		}
+
+		table.Render()
+
+		return buffer.String(), nil
+	}
+}
+
+// Get a table row from preflight check data
+func GetDoctorTableRow(data DoctorCheckData) []string {
+	return []string{data.Check, data.Status, data.Message, data.Hint}
+}
+
+// Formatter for preflight checks
+func FormatDoctorOutput(format string, checks []DoctorCheckData) (string, error) {
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(checks, "", "  ")
+		return string(jsonOut), err
+	case YAML:
+		yamlOut, err := yaml.Marshal(checks)
+		return string(yamlOut), err
+	default:
+		var buffer bytes.Buffer
+		table := tablewriter.NewWriter(&buffer)
+		table.SetHeader(DoctorTableHeaders)
+		table.SetRowLine(true)
+
+		for _, d := range checks {
+			table.Append(GetDoctorTableRow(d))
+		}
+
+		table.Render()
+
+		return buffer.String(), nil
+	}
+}
+
+// Formatter for a pod manifest (e.g. dry-run result). Default to YAML
+func FormatPodOutput(format string, pod *corev1.Pod) (string, error) {
+	if pod == nil {
+		return "", nil
+	}
+
+	switch format {
+	case JSON:
+		jsonOut, err := json.MarshalIndent(pod, "", "  ")
+		return string(jsonOut), err
+	default:
+		yamlOut, err := yaml.Marshal(pod)
+		return string(yamlOut), err
+	}
+}
+
+// Colorize a unified diff with ANSI escape codes (i.e. additions in green, removals in red, hunks in cyan)
+func ColorizeDiff(diff string) string {
+	lines := strings.SplitAfter(diff, "\n")
+	for idx, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[idx] = colorize(line, colorBold)
+		case strings.HasPrefix(line, "+"):
+			lines[idx] = colorize(line, colorGreen)
+		case strings.HasPrefix(line, "-"):
+			lines[idx] = colorize(line, colorRed)
+		case strings.HasPrefix(line, "@@"):
+			lines[idx] = colorize(line, colorCyan)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// Wrap a line (without its trailing newline) in an ANSI color
+func colorize(line, color string) string {
+	content, found := strings.CutSuffix(line, "\n")
+	result := color + content + colorReset
+	if found {
+		result += "\n"
 	}
+	return result
 }
 
 // Formatter for version output
@@ -23,7 +23,10 @@
 package formatter_test
 
 import (
+	"time"
+
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/formatter"
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/profile"
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/version"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -80,6 +83,191 @@ var _ = Describe("Formatter", func() {
 		})
 	})
 
+	Context("when getting ephemeral container states", func() {
+		BeforeEach(func() {
+			t = newTestForPodWithEphemeralContainerStatuses()
+		})
+
+		It("should return the state of each container", func() {
+			data := formatter.GetEphemeralContainersData(t.pod)
+			Expect(data).To(HaveLen(3))
+
+			Expect(data[0].State).To(Equal(formatter.StateTerminated))
+			Expect(data[0].Reason).To(Equal("Completed"))
+			Expect(*data[0].ExitCode).To(BeEquivalentTo(0))
+			Expect(data[0].Command).To(Equal([]string{"sh", "-c", "ls"}))
+
+			Expect(data[1].State).To(Equal(formatter.StateWaiting))
+			Expect(data[1].Reason).To(Equal("ErrImagePull"))
+			Expect(data[1].ExitCode).To(BeNil())
+
+			Expect(data[2].State).To(Equal(formatter.StatePending))
+		})
+	})
+
+	Context("when formatting describe output", func() {
+		Context("with ephemeral containers", func() {
+			BeforeEach(func() {
+				t = newTestForPodWithEphemeralContainerStatuses()
+			})
+
+			It("should return as table", func() {
+				content, err := formatter.FormatDescribeOutput(formatter.Table, t.pod)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(t.describeTable))
+			})
+
+			It("should return as JSON", func() {
+				content, err := formatter.FormatDescribeOutput(formatter.JSON, t.pod)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(t.describeJSON))
+			})
+		})
+
+		Context("without ephemeral containers", func() {
+			BeforeEach(func() {
+				t = newTestForPodWithoutEphemeralContainers()
+			})
+
+			It("should return as table", func() {
+				content, err := formatter.FormatDescribeOutput(formatter.Table, t.pod)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal("Pod: my-pod\nNamespace: default\nEphemeral Containers: <none>\n"))
+			})
+		})
+	})
+
+	Context("when formatting a pod manifest", func() {
+		BeforeEach(func() {
+			t = newTestForPodWithEphemeralContainers()
+		})
+
+		It("should return as YAML by default", func() {
+			content, err := formatter.FormatPodOutput(formatter.Table, &t.pod)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(ContainSubstring("ephemeralContainers:\n  - image: my-image:v1\n    name: debug-container\n"))
+		})
+
+		It("should return as JSON", func() {
+			content, err := formatter.FormatPodOutput(formatter.JSON, &t.pod)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(HavePrefix("{\n  \"metadata\": {\n    \"name\": \"my-pod\""))
+		})
+	})
+
+	Context("when formatting watch events", func() {
+		BeforeEach(func() {
+			t = newTestForPodWithEphemeralContainerStatuses()
+		})
+
+		It("should return a table row with states", func() {
+			row := formatter.GetWatchTableRow("MODIFIED", t.pod)
+			Expect(row).To(Equal([]string{"my-pod", "default", "debug-container (Terminated),another-one (Waiting),pending-one (Pending)", "MODIFIED"}))
+		})
+
+		It("should return an event object per line as JSON", func() {
+			content, err := formatter.FormatWatchEventOutput(formatter.JSON, "ADDED", []corev1.Pod{t.pod, t.pod}, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			event := `{"type":"ADDED","object":{"name":"my-pod","namespace":"default","ephemeralContainers":["debug-container","another-one","pending-one"],"count":3,"images":["my-image:v1","my-image-1:v2"],"targets":["app"],"running":0,"terminated":1,"newestStartedAt":"2024-10-01T08:00:00Z"}}`
+			Expect(content).To(Equal(event + "\n" + event))
+		})
+
+		It("should return an event document as YAML", func() {
+			content, err := formatter.FormatWatchEventOutput(formatter.YAML, "DELETED", []corev1.Pod{t.pod}, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(HavePrefix("---\nobject:\n"))
+			Expect(content).To(HaveSuffix("type: DELETED"))
+		})
+
+		It("should print each pod with a kubectl-compatible printer", func() {
+			content, err := formatter.FormatWatchEventOutput("jsonpath={.metadata.name}", "MODIFIED", []corev1.Pod{t.pod, t.pod}, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(Equal("my-pod\nmy-pod"))
+		})
+	})
+
+	Context("when formatting bulk results", func() {
+		It("should return as table", func() {
+			content, err := formatter.FormatBulkResultOutput(formatter.Table, []formatter.BulkResultData{
+				{Name: "web-0", Namespace: "default", Container: "netshoot", Result: "Added"},
+				{Name: "web-1", Namespace: "default", Container: "netshoot", Result: "Failed", Message: "forbidden"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(Equal(`+-------+-----------+-----------+--------+-----------+
+|  POD  | NAMESPACE | CONTAINER | RESULT |  MESSAGE  |
++-------+-----------+-----------+--------+-----------+
+| web-0 | default   | netshoot  | Added  |           |
+| web-1 | default   | netshoot  | Failed | forbidden |
++-------+-----------+-----------+--------+-----------+
+`))
+		})
+	})
+
+	Context("when formatting doctor checks", func() {
+		It("should return as table", func() {
+			content, err := formatter.FormatDoctorOutput(formatter.Table, []formatter.DoctorCheckData{
+				{Check: "Server version", Status: "Pass", Message: "v1.31.2"},
+				{Check: "Permission get pods/log", Status: "Warn", Message: "denied", Hint: "logs is unavailable"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(Equal(`+-------------------------+--------+---------+---------------------+
+|          CHECK          | STATUS | MESSAGE |        HINT         |
++-------------------------+--------+---------+---------------------+
+| Server version          | Pass   | v1.31.2 |                     |
++-------------------------+--------+---------+---------------------+
+| Permission get pods/log | Warn   | denied  | logs is unavailable |
++-------------------------+--------+---------+---------------------+
+`))
+		})
+	})
+
+	Context("when colorizing a diff", func() {
+		It("should wrap changed lines in colors", func() {
+			diff := "--- a\n+++ b\n@@ -1 +1 @@\n-old\n+new\n same\n"
+
+			Expect(formatter.ColorizeDiff(diff)).To(Equal("\033[1m--- a\033[0m\n\033[1m+++ b\033[0m\n\033[36m@@ -1 +1 @@\033[0m\n\033[31m-old\033[0m\n\033[32m+new\033[0m\n same\n"))
+		})
+	})
+
+	Context("when formatting profile list", func() {
+		var profiles []formatter.ProfileData
+
+		BeforeEach(func() {
+			profiles = []formatter.ProfileData{
+				{
+					Name: "netshoot",
+					Profile: profile.Profile{
+						Image:        "nicolaka/netshoot:v0.13",
+						Command:      []string{"sleep", "infinity"},
+						TargetPolicy: profile.TargetPolicyFirst,
+					},
+				},
+				{
+					Name: "busybox",
+					Profile: profile.Profile{
+						Image:        "busybox:1.28",
+						TargetPolicy: profile.TargetPolicyNamed,
+						Target:       "app",
+					},
+				},
+			}
+		})
+
+		It("should return as table", func() {
+			content, err := formatter.FormatProfileListOutput(formatter.Table, profiles)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(ContainSubstring("| netshoot | nicolaka/netshoot:v0.13 | sleep infinity | first         |"))
+			Expect(content).To(ContainSubstring("| busybox  | busybox:1.28            |                | named (app)   |"))
+		})
+
+		It("should return empty without profiles", func() {
+			content, err := formatter.FormatProfileListOutput(formatter.Table, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(content).To(BeEmpty())
+		})
+	})
+
 	Context("when formatting pod list", func() {
 		Context("with ephemeral containers", func() {
 			BeforeEach(func() {
@@ -103,6 +291,93 @@ var _ = Describe("Formatter", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(content).To(Equal(t.listJSON))
 			})
+
+			It("should return as wide table", func() {
+				t.pod.Spec.NodeName = "node-1"
+
+				content, err := formatter.FormatListOutput(formatter.Wide, []corev1.Pod{t.pod})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(t.listWideTable))
+			})
+		})
+
+		Context("with kubectl-compatible printers", func() {
+			BeforeEach(func() {
+				t = newTestForPodWithEphemeralContainers()
+			})
+
+			DescribeTable("should print the pods", func(format, expected string) {
+				content, err := formatter.FormatListOutput(format, []corev1.Pod{t.pod, t.pod})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(expected))
+			},
+				Entry("with name", formatter.Name, "pod/my-pod\npod/my-pod"),
+				Entry("with jsonpath", "jsonpath={.items[*].spec.ephemeralContainers[*].name}", "debug-container another-one debug-container another-one"),
+				Entry("with go-template", `go-template={{range .items}}{{.metadata.namespace}}/{{.metadata.name}} {{end}}`, "default/my-pod default/my-pod "),
+				Entry("with custom-columns", "custom-columns=POD:.metadata.name,IMAGES:.spec.ephemeralContainers[*].image", "POD      IMAGES\nmy-pod   my-image:v1,my-image-1:v2\nmy-pod   my-image:v1,my-image-1:v2"),
+			)
+
+			It("should reject unknown formats", func() {
+				_, err := formatter.FormatListOutput("xml", []corev1.Pod{t.pod})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(`unsupported output format "xml"`))
+			})
+
+			It("should reject invalid templates", func() {
+				_, err := formatter.FormatListOutput("jsonpath={.items[", []corev1.Pod{t.pod})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("with ephemeral container statuses", func() {
+			BeforeEach(func() {
+				t = newTestForPodWithEphemeralContainerStatuses()
+			})
+
+			It("should summarize the ephemeral containers", func() {
+				data := formatter.ConvertPodsToResourceData([]corev1.Pod{t.pod})
+				Expect(data).To(HaveLen(1))
+				Expect(data[0].Count).To(Equal(3))
+				Expect(data[0].Images).To(Equal([]string{"my-image:v1", "my-image-1:v2"}))
+				Expect(data[0].Targets).To(Equal([]string{"app"}))
+				Expect(data[0].Running).To(Equal(0))
+				Expect(data[0].Terminated).To(Equal(1))
+				Expect(data[0].NewestStartedAt.UTC()).To(Equal(time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)))
+			})
+
+			It("should return a row per ephemeral container as table", func() {
+				content, err := formatter.FormatPerContainerListOutput(formatter.Table, []corev1.Pod{t.pod})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(t.perContainerTable))
+			})
+
+			It("should return a row per ephemeral container as JSON", func() {
+				content, err := formatter.FormatPerContainerListOutput(formatter.JSON, []corev1.Pod{t.pod})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(ContainSubstring(`"container": "debug-container",`))
+				Expect(content).To(ContainSubstring(`"startedAt": "2024-10-01T08:00:00Z",`))
+				Expect(content).To(ContainSubstring(`"exitCode": 0`))
+			})
+
+			DescribeTable("should return a row per ephemeral container with a kubectl-compatible printer", func(format, expected string) {
+				content, err := formatter.FormatPerContainerListOutput(format, []corev1.Pod{t.pod})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(content).To(Equal(expected))
+			},
+				Entry("with jsonpath", "jsonpath={.items[*].state}", "Terminated Waiting Pending"),
+				Entry("with custom-columns", "custom-columns=CONTAINER:.container,STATE:.state", "CONTAINER         STATE\ndebug-container   Terminated\nanother-one       Waiting\npending-one       Pending"),
+			)
+
+			It("should reject the name format for rows", func() {
+				_, err := formatter.FormatPerContainerListOutput(formatter.Name, []corev1.Pod{t.pod})
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("should return the age of the newest ephemeral container in wide table", func() {
+				row := formatter.GetWideTableRow(formatter.ConvertPodsToResourceData([]corev1.Pod{t.pod})[0])
+				Expect(row).To(HaveLen(len(formatter.WideTableHeaders)))
+				Expect(row[len(row)-1]).ToNot(BeEmpty())
+			})
 		})
 		Context("without ephemeral containers", func() {
 			BeforeEach(func() {
@@ -134,9 +409,15 @@ type testInput struct {
 	pod        corev1.Pod
 	containers []string
 
-	listTable string
-	listJSON  string
-	listYAML  string
+	listTable     string
+	listWideTable string
+	listJSON      string
+	listYAML      string
+
+	perContainerTable string
+
+	describeTable string
+	describeJSON  string
 
 	version *version.VersionInfo
 
@@ -199,6 +480,12 @@ func newTestForPodWithEphemeralContainers() *test {
 +--------+-----------+-----------------------------+
 | my-pod | default   | debug-container,another-one |
 +--------+-----------+-----------------------------+
+`
+	t.listWideTable = `+--------+-----------+-----------------------------+--------+-------+---------------------------+---------+---------+------------+-----+
+|  POD   | NAMESPACE |    EPHEMERAL CONTAINERS     |  NODE  | COUNT |          IMAGES           | TARGETS | RUNNING | TERMINATED | AGE |
++--------+-----------+-----------------------------+--------+-------+---------------------------+---------+---------+------------+-----+
+| my-pod | default   | debug-container,another-one | node-1 |     2 | my-image:v1,my-image-1:v2 |         |       0 |          0 |     |
++--------+-----------+-----------------------------+--------+-------+---------------------------+---------+---------+------------+-----+
 `
 	t.listYAML = `[
   {
@@ -207,15 +494,28 @@ func newTestForPodWithEphemeralContainers() *test {
     "ephemeralContainers": [
       "debug-container",
       "another-one"
-    ]
+    ],
+    "count": 2,
+    "images": [
+      "my-image:v1",
+      "my-image-1:v2"
+    ],
+    "running": 0,
+    "terminated": 0
   }
 ]`
 
-	t.listJSON = `- ephemeralContainers:
+	t.listJSON = `- count: 2
+  ephemeralContainers:
   - debug-container
   - another-one
+  images:
+  - my-image:v1
+  - my-image-1:v2
   name: my-pod
   namespace: default
+  running: 0
+  terminated: 0
 `
 	return t
 
@@ -232,3 +532,94 @@ func newTestForPodWithoutEphemeralContainers() *test {
 	}
 	return t
 }
+
+func newTestForPodWithEphemeralContainerStatuses() *test {
+	t := newTestForPodWithEphemeralContainers()
+
+	startedAt := metav1.NewTime(time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC))
+	finishedAt := metav1.NewTime(time.Date(2024, 10, 1, 8, 0, 5, 0, time.UTC))
+
+	t.pod.Spec.EphemeralContainers[0].TargetContainerName = "app"
+	t.pod.Spec.EphemeralContainers[0].Command = []string{"sh", "-c"}
+	t.pod.Spec.EphemeralContainers[0].Args = []string{"ls"}
+	t.pod.Spec.EphemeralContainers = append(t.pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:  "pending-one",
+			Image: "my-image:v1",
+		},
+	})
+	t.pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name: "debug-container",
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Reason:     "Completed",
+					ExitCode:   0,
+					StartedAt:  startedAt,
+					FinishedAt: finishedAt,
+				},
+			},
+		},
+		{
+			Name: "another-one",
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{
+					Reason: "ErrImagePull",
+				},
+			},
+		},
+	}
+
+	t.perContainerTable = `+-----------+--------+-----------------+---------------+--------+------------+----------------------+-----------+
+| NAMESPACE |  POD   |    CONTAINER    |     IMAGE     | TARGET |   STATE    |       STARTED        | EXIT CODE |
++-----------+--------+-----------------+---------------+--------+------------+----------------------+-----------+
+| default   | my-pod | debug-container | my-image:v1   | app    | Terminated | 2024-10-01T08:00:00Z |         0 |
+| default   | my-pod | another-one     | my-image-1:v2 |        | Waiting    |                      |           |
+| default   | my-pod | pending-one     | my-image:v1   |        | Pending    |                      |           |
++-----------+--------+-----------------+---------------+--------+------------+----------------------+-----------+
+`
+
+	t.describeTable = `Pod: my-pod
+Namespace: default
++-----------------+---------------+--------+----------+------------+--------------+-----------+----------------------+----------------------+
+|    CONTAINER    |     IMAGE     | TARGET | COMMAND  |   STATE    |    REASON    | EXIT CODE |       STARTED        |       FINISHED       |
++-----------------+---------------+--------+----------+------------+--------------+-----------+----------------------+----------------------+
+| debug-container | my-image:v1   | app    | sh -c ls | Terminated | Completed    |         0 | 2024-10-01T08:00:00Z | 2024-10-01T08:00:05Z |
+| another-one     | my-image-1:v2 |        |          | Waiting    | ErrImagePull |           |                      |                      |
+| pending-one     | my-image:v1   |        |          | Pending    |              |           |                      |                      |
++-----------------+---------------+--------+----------+------------+--------------+-----------+----------------------+----------------------+
+`
+	t.describeJSON = `{
+  "name": "my-pod",
+  "namespace": "default",
+  "ephemeralContainers": [
+    {
+      "name": "debug-container",
+      "image": "my-image:v1",
+      "target": "app",
+      "command": [
+        "sh",
+        "-c",
+        "ls"
+      ],
+      "state": "Terminated",
+      "reason": "Completed",
+      "exitCode": 0,
+      "startedAt": "2024-10-01T08:00:00Z",
+      "finishedAt": "2024-10-01T08:00:05Z"
+    },
+    {
+      "name": "another-one",
+      "image": "my-image-1:v2",
+      "state": "Waiting",
+      "reason": "ErrImagePull"
+    },
+    {
+      "name": "pending-one",
+      "image": "my-image:v1",
+      "state": "Pending"
+    }
+  ]
+}`
+	return t
+}
@@ -0,0 +1,181 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kubectl/pkg/cmd/get"
+)
+
+const (
+	// kubectl-compatible formats. Except for name, each format takes an argument (e.g. "jsonpath={.items[*].metadata.name}")
+	Name              string = "name"
+	JSONPath          string = "jsonpath"
+	JSONPathFile      string = "jsonpath-file"
+	JSONPathAsJSON    string = "jsonpath-as-json"
+	GoTemplate        string = "go-template"
+	GoTemplateFile    string = "go-template-file"
+	CustomColumns     string = "custom-columns"
+	CustomColumnsFile string = "custom-columns-file"
+)
+
+var (
+	// Formats handled by kubectl-compatible printers
+	PrinterFormats []string = []string{Name, JSONPath, JSONPathFile, JSONPathAsJSON, GoTemplate, GoTemplateFile, CustomColumns, CustomColumnsFile}
+
+	// Formats supported by list output
+	ListFormats []string = append([]string{Table, Wide, JSON, YAML}, PrinterFormats...)
+)
+
+// Check if the format (e.g. "jsonpath=...") is handled by a kubectl-compatible printer
+func IsPrinterFormat(format string) bool {
+	name, _, _ := strings.Cut(format, "=")
+	for _, f := range PrinterFormats {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the format is supported by list output
+func ValidateListFormat(format string) error {
+	switch format {
+	case "", Table, Wide, JSON, YAML:
+		return nil
+	}
+
+	if !IsPrinterFormat(format) {
+		return fmt.Errorf("unsupported output format %q. One of: %s", format, strings.Join(ListFormats, ", "))
+	}
+	return nil
+}
+
+// Format pods with a kubectl-compatible printer
+// The pods are printed as a v1 List (e.g. "jsonpath={.items[*].metadata.name}"). The name and custom-columns printers print a line per pod
+func FormatPodsWithPrinter(format string, pods []corev1.Pod) (string, error) {
+	list := &corev1.PodList{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "List",
+			APIVersion: "v1",
+		},
+	}
+	for _, pod := range pods {
+		item := pod.DeepCopy()
+		item.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+		list.Items = append(list.Items, *item)
+	}
+
+	return FormatWithPrinter(format, list)
+}
+
+// Format a slice of data (e.g. flattened rows) with a kubectl-compatible printer
+// The data is printed as a v1 List of untyped items. The name printer is not supported as items have no kind
+func FormatDataWithPrinter[T any](format string, data []T) (string, error) {
+	if name, _, _ := strings.Cut(format, "="); name == Name {
+		return "", fmt.Errorf("output format %q is only supported for Pods", format)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	var items []map[string]interface{}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return "", err
+	}
+
+	list := &unstructured.UnstructuredList{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+		},
+	}
+	for _, item := range items {
+		list.Items = append(list.Items, unstructured.Unstructured{Object: item})
+	}
+
+	return FormatWithPrinter(format, list)
+}
+
+// Format an object (or a v1 List of objects) with a kubectl-compatible printer
+func FormatWithPrinter(format string, obj runtime.Object) (string, error) {
+	printer, err := newPrinter(format)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	if name, _, _ := strings.Cut(format, "="); name == Name {
+		// The name printer does not support typed lists
+		items := []runtime.Object{obj}
+		if meta.IsListType(obj) {
+			if items, err = meta.ExtractList(obj); err != nil {
+				return "", err
+			}
+		}
+		for _, item := range items {
+			if err := printer.PrintObj(item, &buffer); err != nil {
+				return "", err
+			}
+		}
+	} else if err := printer.PrintObj(obj, &buffer); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// Construct a kubectl-compatible printer from the format
+func newPrinter(format string) (printers.ResourcePrinter, error) {
+	name, arg, _ := strings.Cut(format, "=")
+	switch name {
+	case CustomColumns:
+		return get.NewCustomColumnsPrinterFromSpec(arg, scheme.Codecs.UniversalDecoder(), false)
+	case CustomColumnsFile:
+		file, err := os.Open(arg)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template %s: %w", arg, err)
+		}
+		defer file.Close()
+		return get.NewCustomColumnsPrinterFromTemplate(file, scheme.Codecs.UniversalDecoder())
+	}
+
+	printFlags := genericclioptions.NewPrintFlags("").WithTypeSetter(scheme.Scheme)
+	printFlags.OutputFormat = &format
+	return printFlags.ToPrinter()
+}
@@ -0,0 +1,178 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Outcome of adding an ephemeral container to a pod in bulk
+type BulkResult string
+
+const (
+	BulkAdded   BulkResult = "Added"
+	BulkSkipped BulkResult = "Skipped" // The pod already has the ephemeral container
+	BulkFailed  BulkResult = "Failed"
+)
+
+const (
+	DEFAULT_BULK_CONCURRENCY int     = 5
+	DEFAULT_BULK_QPS         float32 = 5
+	DEFAULT_BULK_MAX_PODS    int     = 50
+)
+
+// Options to add ephemeral containers to pods in bulk
+type BulkOptions struct {
+	// Maximum number of concurrent update requests
+	Concurrency int
+	// Maximum number of update requests per second
+	QPS float32
+	// Dry-run strategy for update requests
+	DryRun DryRunStrategy
+}
+
+// Result of adding an ephemeral container to a pod
+type BulkAddResult struct {
+	Namespace string
+	Pod       string
+	Container string
+	Result    BulkResult
+	Err       error
+}
+
+// Constructor for the ephemeral container to add to a pod
+type EphemeralContainerFn func(pod *corev1.Pod) (*corev1.EphemeralContainer, error)
+
+// Check if the name is a glob pattern (e.g. web-*) rather than a pod name
+func IsNamePattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// Filter pods by names matching a glob pattern
+func NamePatternFilter(pattern string) PodFilterFn {
+	return func(pod corev1.Pod) bool {
+		matched, err := path.Match(pattern, pod.Name)
+		return err == nil && matched
+	}
+}
+
+// Add an ephemeral container to each pod with limited concurrency and rate
+// Pods that already have an ephemeral container with the same name are skipped
+// Results are returned in the same order as pods
+func (client *KubeClientset) AddEphemeralContainerToPods(ctx context.Context, pods []corev1.Pod, newContainer EphemeralContainerFn, opts BulkOptions) []BulkAddResult {
+	results := make([]BulkAddResult, len(pods))
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var limiter flowcontrol.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+	if opts.QPS > 0 {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(opts.QPS, 1)
+	}
+	defer limiter.Stop()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for idx := range pods {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[idx] = client.addEphemeralContainerToPod(ctx, &pods[idx], newContainer, limiter, opts.DryRun)
+		}(idx)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Add an ephemeral container to a single pod, waiting for the rate limiter before the update request
+func (client *KubeClientset) addEphemeralContainerToPod(ctx context.Context, pod *corev1.Pod, newContainer EphemeralContainerFn, limiter flowcontrol.RateLimiter, dryRun DryRunStrategy) BulkAddResult {
+	result := BulkAddResult{Namespace: pod.Namespace, Pod: pod.Name}
+
+	container, err := newContainer(pod)
+	if err != nil {
+		result.Result, result.Err = BulkFailed, err
+		return result
+	}
+	result.Container = container.Name
+
+	if FindEphemeralContainer(pod, container.Name) != nil {
+		result.Result = BulkSkipped
+		return result
+	}
+
+	editedPod, err := AddEphemeralContainer(pod, container)
+	if err != nil {
+		result.Result, result.Err = BulkFailed, err
+		return result
+	}
+
+	patch, err := SanitizeEditedPod(pod, editedPod)
+	if err != nil {
+		result.Result, result.Err = BulkFailed, err
+		return result
+	}
+
+	if dryRun != DryRunClient {
+		if err = limiter.Wait(ctx); err != nil {
+			result.Result, result.Err = BulkFailed, err
+			return result
+		}
+
+		var opts []UpdateOption
+		if dryRun == DryRunServer {
+			opts = append(opts, WithDryRun())
+		}
+
+		if _, err = client.UpdateEphemeralContainersForPod(ctx, patch, opts...); err != nil {
+			result.Result, result.Err = BulkFailed, err
+			return result
+		}
+	}
+
+	result.Result = BulkAdded
+	return result
+}
+
+// Summarize bulk results (e.g. "2 added, 1 skipped, 0 failed")
+func SummarizeBulkResults(results []BulkAddResult) (summary string, failed int) {
+	counts := make(map[BulkResult]int)
+	for _, result := range results {
+		counts[result.Result]++
+	}
+
+	return fmt.Sprintf("%d added, %d skipped, %d failed", counts[BulkAdded], counts[BulkSkipped], counts[BulkFailed]), counts[BulkFailed]
+}
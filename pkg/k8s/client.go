@@ -37,7 +37,6 @@ import (
 
 var (
 	NAMESPACE_DEFAULT string = "default"
-	noopFunc          func() = func() {}
 )
 
 // Represent context with a cancel func
@@ -48,7 +47,7 @@ type ContextOptions struct {
 }
 
 // Set up the options with the following steps:
-// * Create a Context with timeout if any. Otherwise, no timeout is set (i.e. context.Background())
+// * Create a Context with timeout if any. Otherwise, no timeout is set (i.e. only cancelled on signals)
 // * Create a chan os.Signal to handle SIGTERM, SIGINT (Ctrl + C),SIGHUP (terminal is closed)
 func (opts *ContextOptions) InitContext(timeout *string) error {
 	// Global context
@@ -63,7 +62,7 @@ func (opts *ContextOptions) InitContext(timeout *string) error {
 		}
 		ctx, cancel = context.WithTimeout(context.Background(), duration)
 	} else {
-		ctx, cancel = context.Background(), noopFunc
+		ctx, cancel = context.WithCancel(context.Background())
 	}
 
 	opts.SigChan = make(chan os.Signal, 1)
@@ -125,5 +124,6 @@ func NewClientset(kubeConfig *KubeConfig) (*KubeClientset, error) {
 
 	return &KubeClientset{
 		Interface: _clientset,
+		Config:    config,
 	}, nil
 }
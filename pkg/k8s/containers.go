@@ -0,0 +1,308 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Prefix for generated ephemeral container names
+	CONTAINER_NAME_PREFIX string = "debugger"
+)
+
+// Options to construct an ephemeral container
+type EphemeralContainerOptions struct {
+	// Container name. If empty, a name is generated
+	Name string
+	// Container image
+	Image string
+	// Image pull policy. If empty, the API server default is used
+	ImagePullPolicy string
+	// Name of the container in the pod to target (i.e. share process namespace)
+	TargetContainer string
+	// Environment variables in the form of KEY=VALUE
+	Env []string
+	// Entrypoint for the container
+	Command []string
+	// Allocate a buffer for stdin
+	Stdin bool
+	// Allocate a TTY
+	TTY bool
+}
+
+// Construct an ephemeral container from options
+func NewEphemeralContainer(opts *EphemeralContainerOptions) (*corev1.EphemeralContainer, error) {
+	env, err := ParseEnvVars(opts.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.Name
+	if len(name) == 0 {
+		name = GenerateContainerName()
+	}
+
+	container := &corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    opts.Image,
+			ImagePullPolicy:          corev1.PullPolicy(opts.ImagePullPolicy),
+			Env:                      env,
+			Command:                  opts.Command,
+			Stdin:                    opts.Stdin,
+			TTY:                      opts.TTY,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+		TargetContainerName: opts.TargetContainer,
+	}
+
+	return container, nil
+}
+
+// Generate a random name for an ephemeral container
+func GenerateContainerName() string {
+	return fmt.Sprintf("%s-%s", CONTAINER_NAME_PREFIX, utilrand.String(5))
+}
+
+// Parse environment variables in the form of KEY=VALUE
+func ParseEnvVars(vars []string) ([]corev1.EnvVar, error) {
+	var env []corev1.EnvVar
+	for _, v := range vars {
+		key, value, found := strings.Cut(v, "=")
+		if !found || len(key) == 0 {
+			return nil, fmt.Errorf("invalid environment variable %q. Expected format: KEY=VALUE", v)
+		}
+		env = append(env, corev1.EnvVar{Name: key, Value: value})
+	}
+	return env, nil
+}
+
+// Parse a partial container spec in YAML or JSON to customize ephemeral containers
+func ParseCustomContainer(content []byte) (*corev1.Container, error) {
+	custom := &corev1.Container{}
+	if err := yaml.UnmarshalStrict(content, custom); err != nil {
+		return nil, errors.Join(errors.New("failed to parse custom container spec"), err)
+	}
+
+	if err := ValidateCustomContainer(custom).ToAggregate(); err != nil {
+		return nil, errors.Join(errors.New("invalid custom container spec"), err)
+	}
+
+	return custom, nil
+}
+
+// Validate a partial container spec to customize ephemeral containers
+// The fields not supported by ephemeral containers are forbidden. The name can only be set with flags
+func ValidateCustomContainer(custom *corev1.Container) (errs field.ErrorList) {
+	unsupported := func(name string) *field.Error {
+		return field.Forbidden(field.NewPath(name), "ephemeral containers do not support this field")
+	}
+
+	if len(custom.Name) > 0 {
+		errs = append(errs, field.Forbidden(field.NewPath("name"), "name cannot be customized. Use --name instead"))
+	}
+	if len(custom.Ports) > 0 {
+		errs = append(errs, unsupported("ports"))
+	}
+	if custom.LivenessProbe != nil {
+		errs = append(errs, unsupported("livenessProbe"))
+	}
+	if custom.ReadinessProbe != nil {
+		errs = append(errs, unsupported("readinessProbe"))
+	}
+	if custom.StartupProbe != nil {
+		errs = append(errs, unsupported("startupProbe"))
+	}
+	if custom.Lifecycle != nil {
+		errs = append(errs, unsupported("lifecycle"))
+	}
+	if len(custom.Resources.Limits) > 0 || len(custom.Resources.Requests) > 0 || len(custom.Resources.Claims) > 0 {
+		errs = append(errs, unsupported("resources"))
+	}
+	if len(custom.ResizePolicy) > 0 {
+		errs = append(errs, unsupported("resizePolicy"))
+	}
+	if custom.RestartPolicy != nil {
+		errs = append(errs, unsupported("restartPolicy"))
+	}
+
+	return errs
+}
+
+// Strategic-merge a partial container spec onto a copy of the ephemeral container
+// Lists with merge keys (e.g. env, volumeMounts) are merged by key. Other fields in the custom spec take precedence
+func MergeCustomContainer(container *corev1.EphemeralContainer, custom *corev1.Container) (*corev1.EphemeralContainer, error) {
+	custom = custom.DeepCopy()
+	custom.Name = container.Name
+
+	customJSON, err := json.Marshal(custom)
+	if err != nil {
+		return nil, err
+	}
+
+	containerJSON, err := json.Marshal(container)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedJSON, err := strategicpatch.StrategicMergePatch(containerJSON, customJSON, corev1.Container{})
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to merge custom container spec into ephemeral container %s", container.Name), err)
+	}
+
+	merged := &corev1.EphemeralContainer{}
+	if err = json.Unmarshal(mergedJSON, merged); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// Copy the identity (i.e. user, group and SELinux options) of the target container into the ephemeral container
+// Settings of the target container take precedence over the pod's security context.
+// Pod-level supplementalGroups and fsGroup need no copy because they already apply to ephemeral containers
+func MatchTargetIdentity(pod *corev1.Pod, container *corev1.EphemeralContainer) error {
+	if len(container.TargetContainerName) == 0 {
+		return fmt.Errorf("ephemeral container %s has no target container to match", container.Name)
+	}
+
+	var target *corev1.Container
+	for idx := range pod.Spec.Containers {
+		if pod.Spec.Containers[idx].Name == container.TargetContainerName {
+			target = &pod.Spec.Containers[idx]
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("target container %s not found in pod/%s", container.TargetContainerName, pod.Name)
+	}
+
+	podContext := pod.Spec.SecurityContext
+	if podContext == nil {
+		podContext = &corev1.PodSecurityContext{}
+	}
+	targetContext := target.SecurityContext
+	if targetContext == nil {
+		targetContext = &corev1.SecurityContext{}
+	}
+
+	if container.SecurityContext == nil {
+		container.SecurityContext = &corev1.SecurityContext{}
+	}
+	securityContext := container.SecurityContext
+
+	// Fields set by neither the target nor the pod keep their values (e.g. from profiles)
+	securityContext.RunAsUser = firstNonNil(targetContext.RunAsUser, podContext.RunAsUser, securityContext.RunAsUser)
+	securityContext.RunAsGroup = firstNonNil(targetContext.RunAsGroup, podContext.RunAsGroup, securityContext.RunAsGroup)
+	securityContext.RunAsNonRoot = firstNonNil(targetContext.RunAsNonRoot, podContext.RunAsNonRoot, securityContext.RunAsNonRoot)
+	securityContext.SELinuxOptions = firstNonNil(targetContext.SELinuxOptions, podContext.SELinuxOptions, securityContext.SELinuxOptions)
+
+	// Keep copies so that the ephemeral container does not share pointers with the pod
+	*securityContext = *securityContext.DeepCopy()
+	if *securityContext == (corev1.SecurityContext{}) {
+		container.SecurityContext = nil
+	}
+
+	return nil
+}
+
+func firstNonNil[T any](values ...*T) *T {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// Add an ephemeral container to a copy of the pod
+// The container's name must be unique within the pod and its target (if any) must exist
+func AddEphemeralContainer(pod *corev1.Pod, container *corev1.EphemeralContainer) (*corev1.Pod, error) {
+	if len(container.Image) == 0 {
+		return nil, fmt.Errorf("image is required for ephemeral container %s", container.Name)
+	}
+
+	if HasContainer(pod, container.Name) {
+		return nil, fmt.Errorf("container %s already exists in pod/%s", container.Name, pod.Name)
+	}
+
+	if len(container.TargetContainerName) > 0 && !hasRegularContainer(pod, container.TargetContainerName) {
+		return nil, fmt.Errorf("target container %s not found in pod/%s", container.TargetContainerName, pod.Name)
+	}
+
+	result := pod.DeepCopy()
+	result.Spec.EphemeralContainers = append(result.Spec.EphemeralContainers, *container.DeepCopy())
+
+	return result, nil
+}
+
+// Check if a container with the name exists in the pod (i.e. in containers, initContainers or ephemeralContainers)
+func HasContainer(pod *corev1.Pod, name string) bool {
+	if hasRegularContainer(pod, name) {
+		return true
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name {
+			return true
+		}
+	}
+
+	for _, container := range pod.Spec.EphemeralContainers {
+		if container.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if a container with the name exists in pod.spec.containers
+func hasRegularContainer(pod *corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Find an ephemeral container by name. Nil if not found
+func FindEphemeralContainer(pod *corev1.Pod, name string) *corev1.EphemeralContainer {
+	for idx := range pod.Spec.EphemeralContainers {
+		if pod.Spec.EphemeralContainers[idx].Name == name {
+			return &pod.Spec.EphemeralContainers[idx]
+		}
+	}
+	return nil
+}
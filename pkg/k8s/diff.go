@@ -0,0 +1,174 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// Differences between the original and edited ephemeral containers of a pod
+type EphemeralContainersDiff struct {
+	// Names of new ephemeral containers in edited order
+	Added []string
+	// Names of existing ephemeral containers with changed spec
+	Modified []string
+	// Names of existing ephemeral containers missing in edited list
+	Removed []string
+	// Whether existing ephemeral containers are in different order
+	Reordered bool
+	// Field-level errors for illegal changes
+	Errors field.ErrorList
+}
+
+// Compare ephemeral containers per container (i.e. by name)
+// Existing ephemeral containers may not be changed or removed. Such changes are reported as field-level errors
+func DiffEphemeralContainers(original, edited []corev1.EphemeralContainer) *EphemeralContainersDiff {
+	diff := &EphemeralContainersDiff{}
+	fldPath := field.NewPath("spec", "ephemeralContainers")
+
+	originalByName := make(map[string]corev1.EphemeralContainer, len(original))
+	for _, container := range original {
+		originalByName[container.Name] = container
+	}
+
+	editedNames := make(map[string]bool, len(edited))
+	existingOrder := make([]string, 0, len(original))
+	for idx, container := range edited {
+		if len(container.Name) == 0 {
+			diff.Errors = append(diff.Errors, field.Required(fldPath.Index(idx).Child("name"), "ephemeral container name is required"))
+			continue
+		}
+
+		if editedNames[container.Name] {
+			diff.Errors = append(diff.Errors, field.Duplicate(fldPath.Key(container.Name).Child("name"), container.Name))
+			continue
+		}
+		editedNames[container.Name] = true
+
+		existing, found := originalByName[container.Name]
+		if !found {
+			diff.Added = append(diff.Added, container.Name)
+			continue
+		}
+		existingOrder = append(existingOrder, container.Name)
+
+		if errs := diffEphemeralContainer(fldPath.Key(container.Name), existing, container); len(errs) > 0 {
+			diff.Modified = append(diff.Modified, container.Name)
+			diff.Errors = append(diff.Errors, errs...)
+		}
+	}
+
+	originalOrder := make([]string, 0, len(original))
+	for _, container := range original {
+		if !editedNames[container.Name] {
+			diff.Removed = append(diff.Removed, container.Name)
+			diff.Errors = append(diff.Errors, field.Forbidden(fldPath.Key(container.Name), "existing ephemeral container may not be removed"))
+			continue
+		}
+		originalOrder = append(originalOrder, container.Name)
+	}
+
+	diff.Reordered = !reflect.DeepEqual(originalOrder, existingOrder)
+
+	return diff
+}
+
+// Check if there are no changes, ignoring order
+func (d *EphemeralContainersDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Modified) == 0 && len(d.Removed) == 0 && len(d.Errors) == 0
+}
+
+// Compare the spec of an existing ephemeral container field by field, ignoring fields defaulted by the API server if unset
+func diffEphemeralContainer(fldPath *field.Path, original, edited corev1.EphemeralContainer) field.ErrorList {
+	return diffFields(fldPath, reflect.ValueOf(*withEphemeralContainerDefaults(original)), reflect.ValueOf(*withEphemeralContainerDefaults(edited)))
+}
+
+// Compare struct fields by their JSON names. Inlined structs are compared recursively
+func diffFields(fldPath *field.Path, original, edited reflect.Value) (errs field.ErrorList) {
+	for i := 0; i < original.NumField(); i++ {
+		structField := original.Type().Field(i)
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+
+		originalValue, editedValue := original.Field(i), edited.Field(i)
+		if len(name) == 0 && originalValue.Kind() == reflect.Struct {
+			errs = append(errs, diffFields(fldPath, originalValue, editedValue)...)
+			continue
+		}
+
+		if !equality.Semantic.DeepEqual(originalValue.Interface(), editedValue.Interface()) {
+			errs = append(errs, field.Forbidden(fldPath.Child(name),
+				fmt.Sprintf("existing ephemeral container may not be changed, was %s, now %s", formatFieldValue(originalValue), formatFieldValue(editedValue))))
+		}
+	}
+
+	return errs
+}
+
+// Format a field value as JSON for error messages
+func formatFieldValue(value reflect.Value) string {
+	content, err := json.Marshal(value.Interface())
+	if err != nil {
+		return fmt.Sprintf("%v", value.Interface())
+	}
+	return string(content)
+}
+
+// Get a unified diff of spec.ephemeralContainers (in YAML) between the original pod and the patch to submit
+func UnifiedDiffEphemeralContainers(original, patch *corev1.Pod) (string, error) {
+	originalContent, err := marshalEphemeralContainers(original)
+	if err != nil {
+		return "", err
+	}
+
+	patchContent, err := marshalEphemeralContainers(patch)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(originalContent),
+		B:        difflib.SplitLines(patchContent),
+		FromFile: fmt.Sprintf("pod/%s (original)", original.Name),
+		ToFile:   fmt.Sprintf("pod/%s (edited)", original.Name),
+		Context:  3,
+	})
+}
+
+// Marshal spec.ephemeralContainers of a pod to YAML, keeping the field path
+func marshalEphemeralContainers(pod *corev1.Pod) (string, error) {
+	content, err := yaml.Marshal(map[string]any{
+		"spec": map[string]any{
+			"ephemeralContainers": pod.Spec.EphemeralContainers,
+		},
+	})
+	return string(content), err
+}
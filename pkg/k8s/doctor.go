@@ -0,0 +1,235 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// Status of a preflight check
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "Pass"
+	CheckWarn CheckStatus = "Warn"
+	CheckFail CheckStatus = "Fail"
+)
+
+const (
+	// The ephemeralcontainers subresource is GA since Kubernetes v1.25
+	MIN_SERVER_VERSION string = "v1.25.0"
+
+	// Pod Security Admission labels on namespaces
+	// See: https://kubernetes.io/docs/concepts/security/pod-security-admission/#pod-security-admission-labels-for-namespaces
+	PodSecurityEnforceLabel        string = "pod-security.kubernetes.io/enforce"
+	PodSecurityEnforceVersionLabel string = "pod-security.kubernetes.io/enforce-version"
+)
+
+// Result of a preflight check
+type CheckResult struct {
+	Name    string
+	Status  CheckStatus
+	Message string
+	Hint    string // How to fix the issue if the check does not pass
+}
+
+// Access required by the plugin in a namespace
+type accessCheck struct {
+	verb        string
+	subresource string
+	usedBy      string
+	required    bool // If false, a denied access only affects some commands
+}
+
+var (
+	accessChecks = []accessCheck{
+		{verb: "get", usedBy: "all commands", required: true},
+		{verb: "list", usedBy: "list, logs and bulk add", required: true},
+		{verb: "update", subresource: "ephemeralcontainers", usedBy: "edit, add and apply", required: true},
+		{verb: "patch", subresource: "ephemeralcontainers", usedBy: "kubectl debug"},
+		{verb: "create", subresource: "attach", usedBy: "attach"},
+		{verb: "get", subresource: "log", usedBy: "logs"},
+	}
+)
+
+// Run preflight checks for the server version, the ephemeralcontainers subresource,
+// permissions and Pod Security in a namespace
+func (client *KubeClientset) RunPreflightChecks(ctx context.Context, namespace string) []CheckResult {
+	results := []CheckResult{
+		client.checkServerVersion(),
+		client.checkEphemeralContainersSubresource(),
+	}
+
+	for _, check := range accessChecks {
+		results = append(results, client.checkAccess(ctx, namespace, check))
+	}
+
+	return append(results, client.checkPodSecurity(ctx, namespace))
+}
+
+// Check if any preflight check failed
+func HasFailedCheck(results []CheckResult) bool {
+	for _, result := range results {
+		if result.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the server version supports the ephemeralcontainers subresource
+func (client *KubeClientset) checkServerVersion() CheckResult {
+	result := CheckResult{Name: "Server version"}
+
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		result.Status, result.Message = CheckFail, fmt.Sprintf("failed to get server version: %s", err.Error())
+		result.Hint = "Check the connection to the cluster (e.g. --kubeconfig and --context)"
+		return result
+	}
+
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		result.Status, result.Message = CheckWarn, fmt.Sprintf("unable to parse server version %q", info.GitVersion)
+		result.Hint = fmt.Sprintf("Ensure the server version is at least %s", MIN_SERVER_VERSION)
+		return result
+	}
+
+	if !serverVersion.AtLeast(version.MustParseGeneric(MIN_SERVER_VERSION)) {
+		result.Status, result.Message = CheckFail, fmt.Sprintf("server version %s is older than %s", info.GitVersion, MIN_SERVER_VERSION)
+		result.Hint = fmt.Sprintf("Upgrade the cluster to %s or later", MIN_SERVER_VERSION)
+		return result
+	}
+
+	result.Status, result.Message = CheckPass, fmt.Sprintf("server version %s", info.GitVersion)
+	return result
+}
+
+// Check if the API server serves the pods/ephemeralcontainers subresource
+func (client *KubeClientset) checkEphemeralContainersSubresource() CheckResult {
+	result := CheckResult{Name: "Subresource pods/ephemeralcontainers"}
+
+	resources, err := client.Discovery().ServerResourcesForGroupVersion(corev1.SchemeGroupVersion.String())
+	if err != nil {
+		result.Status, result.Message = CheckFail, fmt.Sprintf("failed to discover resources in %s: %s", corev1.SchemeGroupVersion.String(), err.Error())
+		result.Hint = "Check the connection to the cluster (e.g. --kubeconfig and --context)"
+		return result
+	}
+
+	for _, resource := range resources.APIResources {
+		if resource.Name == "pods/ephemeralcontainers" {
+			result.Status, result.Message = CheckPass, fmt.Sprintf("served with verbs %v", resource.Verbs)
+			return result
+		}
+	}
+
+	result.Status, result.Message = CheckFail, "not served by the API server"
+	result.Hint = "Enable the EphemeralContainers feature gate on the API server or upgrade the cluster"
+	return result
+}
+
+// Check if the current user is allowed to access pods (or a subresource) with a SelfSubjectAccessReview
+func (client *KubeClientset) checkAccess(ctx context.Context, namespace string, check accessCheck) CheckResult {
+	resource := "pods"
+	if len(check.subresource) > 0 {
+		resource += "/" + check.subresource
+	}
+	result := CheckResult{Name: fmt.Sprintf("Permission %s %s", check.verb, resource)}
+
+	review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        check.verb,
+				Resource:    "pods",
+				Subresource: check.subresource,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		result.Status, result.Message = CheckWarn, fmt.Sprintf("failed to review access: %s", err.Error())
+		return result
+	}
+
+	if review.Status.Allowed {
+		result.Status, result.Message = CheckPass, fmt.Sprintf("allowed in namespace %s", namespace)
+		return result
+	}
+
+	result.Status = CheckWarn
+	if check.required {
+		result.Status = CheckFail
+	}
+	result.Message = fmt.Sprintf("denied in namespace %s (used by %s)", namespace, check.usedBy)
+	if len(review.Status.Reason) > 0 {
+		result.Message += ": " + review.Status.Reason
+	}
+	result.Hint = fmt.Sprintf("Ask a cluster administrator for a Role granting %q on %q in namespace %s", check.verb, resource, namespace)
+	return result
+}
+
+// Check the Pod Security level enforced in the namespace
+func (client *KubeClientset) checkPodSecurity(ctx context.Context, namespace string) CheckResult {
+	result := CheckResult{Name: "Pod Security"}
+
+	ns, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		result.Status, result.Message = CheckWarn, errors.Join(fmt.Errorf("unable to read labels of namespace %s", namespace), err).Error()
+		result.Hint = "Ask a cluster administrator which Pod Security level is enforced in the namespace"
+		return result
+	}
+
+	level, found := ns.Labels[PodSecurityEnforceLabel]
+	if !found {
+		result.Status, result.Message = CheckPass, fmt.Sprintf("no level enforced in namespace %s", namespace)
+		return result
+	}
+
+	levelVersion := ns.Labels[PodSecurityEnforceVersionLabel]
+	if len(levelVersion) == 0 {
+		levelVersion = "latest"
+	}
+	result.Message = fmt.Sprintf("level %s (version %s) enforced in namespace %s", level, levelVersion, namespace)
+
+	switch level {
+	case "privileged":
+		result.Status = CheckPass
+	case "baseline":
+		result.Status = CheckWarn
+		result.Hint = "Ephemeral containers must not be privileged or add capabilities beyond the baseline set (e.g. NET_ADMIN, SYS_PTRACE)"
+	case "restricted":
+		result.Status = CheckWarn
+		result.Hint = "Ephemeral containers must run as non-root, drop ALL capabilities, disallow privilege escalation and use the RuntimeDefault seccomp profile"
+	default:
+		result.Status = CheckWarn
+		result.Message = fmt.Sprintf("unknown level %q enforced in namespace %s", level, namespace)
+	}
+	return result
+}
@@ -23,16 +23,32 @@
 package k8s_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/k8s"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	apiversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	psaapi "k8s.io/pod-security-admission/api"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("K8s", func() {
@@ -83,18 +99,142 @@ var _ = Describe("K8s", func() {
 	When("listing pods", func() {
 		Context("in a namespace", func() {
 			It("should return pods", func() {
-				pods, err := t.clientset.ListPods(context.Background(), t.namespaces[0])
+				pods, err := t.clientset.ListPods(context.Background(), t.namespaces[0], metav1.ListOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(pods).To(HaveLen(1))
 			})
 		})
 		Context("in all namespaces", func() {
 			It("should return pods", func() {
-				pods, err := t.clientset.ListPods(context.Background(), "")
+				pods, err := t.clientset.ListPods(context.Background(), "", metav1.ListOptions{})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(pods).To(HaveLen(2))
 			})
 		})
+		Context("in chunks", func() {
+			var calls int
+
+			BeforeEach(func() {
+				calls = 0
+				pages := []func() (runtime.Object, error){
+					func() (runtime.Object, error) { return t.newPodList("c1", "pod-0"), nil },
+					func() (runtime.Object, error) {
+						return nil, apierrors.NewResourceExpired("continue token expired")
+					},
+					func() (runtime.Object, error) { return t.newPodList("c1", "pod-0"), nil },
+					func() (runtime.Object, error) { return t.newPodList("", "pod-1"), nil },
+				}
+
+				t.clientset.Interface.(*fake.Clientset).PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					page := pages[calls]
+					calls++
+					obj, err := page()
+					return true, obj, err
+				})
+			})
+
+			It("should restart if the continue token expires", func() {
+				pods, err := t.clientset.ListPods(context.Background(), "", metav1.ListOptions{Limit: 1})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(calls).To(Equal(4))
+
+				names := []string{}
+				for _, pod := range pods {
+					names = append(names, pod.Name)
+				}
+				Expect(names).To(Equal([]string{"pod-0", "pod-1"}))
+			})
+		})
+		Context("with a label selector", func() {
+			It("should return matching pods", func() {
+				pod, err := t.clientset.GetPod(context.Background(), t.namespaces[1], "testpod")
+				Expect(err).ToNot(HaveOccurred())
+
+				pod.Labels = map[string]string{"app": "web"}
+				_, err = t.clientset.CoreV1().Pods(pod.Namespace).Update(context.Background(), pod, metav1.UpdateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				pods, err := t.clientset.ListPods(context.Background(), "", metav1.ListOptions{LabelSelector: "app=web"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pods).To(HaveLen(1))
+				Expect(pods[0].Namespace).To(Equal(t.namespaces[1]))
+			})
+		})
+	})
+
+	When("filtering pods", func() {
+		var pods []corev1.Pod
+
+		BeforeEach(func() {
+			pods = []corev1.Pod{*t.newPod("testpod", t.namespaces[0]), *t.newPod("anotherpod", t.namespaces[0])}
+			pods[0].Labels = map[string]string{"app": "web"}
+		})
+
+		It("should return pods matching all filters", func() {
+			hasEphemeralContainers := func(pod corev1.Pod) bool {
+				return len(pod.Spec.EphemeralContainers) > 0
+			}
+			isWeb := func(pod corev1.Pod) bool {
+				return pod.Labels["app"] == "web"
+			}
+
+			result := k8s.ApplyPodFilter(pods, hasEphemeralContainers, isWeb)
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Name).To(Equal("testpod"))
+		})
+
+		It("should not duplicate pods matching several filters", func() {
+			always := func(pod corev1.Pod) bool {
+				return true
+			}
+
+			result := k8s.ApplyPodFilter(pods, always, always)
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].Name).To(Equal("testpod"))
+			Expect(result[1].Name).To(Equal("anotherpod"))
+		})
+	})
+
+	When("streaming logs", func() {
+		var pods []corev1.Pod
+
+		BeforeEach(func() {
+			pods = []corev1.Pod{*t.newPod("testpod", t.namespaces[0]), *t.newPod("testpod", t.namespaces[1])}
+		})
+
+		It("should get targets for ephemeral containers", func() {
+			targets := k8s.GetLogTargets(pods, "")
+			Expect(targets).To(Equal([]k8s.LogTarget{
+				{Namespace: t.namespaces[0], Pod: "testpod", Container: "debugger"},
+				{Namespace: t.namespaces[1], Pod: "testpod", Container: "debugger"},
+			}))
+
+			Expect(k8s.GetLogTargets(pods, "not-a-container")).To(BeEmpty())
+		})
+
+		It("should prefix lines when merging logs", func() {
+			var buffer bytes.Buffer
+			targets := k8s.GetLogTargets(pods, "debugger")
+
+			err := t.clientset.StreamLogs(context.Background(), targets, &corev1.PodLogOptions{}, &buffer, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Fake clientset returns "fake logs" for all containers
+			lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+			Expect(lines).To(ConsistOf(
+				fmt.Sprintf("[%s/testpod/debugger] fake logs", t.namespaces[0]),
+				fmt.Sprintf("[%s/testpod/debugger] fake logs", t.namespaces[1]),
+			))
+		})
+
+		It("should not prefix lines otherwise", func() {
+			var buffer bytes.Buffer
+			targets := k8s.GetLogTargets(pods[:1], "debugger")
+
+			err := t.clientset.StreamLogs(context.Background(), targets, &corev1.PodLogOptions{}, &buffer, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer.String()).To(Equal("fake logs\n"))
+		})
 	})
 
 	When("getting a pod", func() {
@@ -131,6 +271,1066 @@ var _ = Describe("K8s", func() {
 
 			Expect(pod.Spec.EphemeralContainers).To(ContainElement(newCont))
 		})
+
+		Context("when the pod is modified concurrently", func() {
+			var pod *corev1.Pod
+			var conflicts int
+
+			JustBeforeEach(func() {
+				var err error
+				pod, err = t.clientset.GetPod(context.Background(), t.namespaces[0], "testpod")
+				Expect(err).ToNot(HaveOccurred())
+
+				// Simulate a colleague adding an ephemeral container in the meantime
+				latest := pod.DeepCopy()
+				latest.Spec.EphemeralContainers = append(latest.Spec.EphemeralContainers, *t.newEphemeralContainer("colleague", ""))
+				_, err = t.clientset.CoreV1().Pods(latest.Namespace).UpdateEphemeralContainers(context.Background(), latest.Name, latest, metav1.UpdateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+
+				conflicts = 0
+				t.clientset.Interface.(*fake.Clientset).PrependReactor("update", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if action.GetSubresource() != "ephemeralcontainers" || conflicts > 0 {
+						return false, nil, nil
+					}
+					conflicts++
+					return true, nil, apierrors.NewConflict(corev1.Resource("pods"), pod.Name, errors.New("the object has been modified"))
+				})
+			})
+
+			It("should re-apply only the added containers on top of the latest pod", func() {
+				pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *t.newEphemeralContainer("mine", ""))
+
+				result, err := t.clientset.UpdateEphemeralContainersForPod(context.Background(), pod)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conflicts).To(Equal(1))
+
+				names := []string{}
+				for _, container := range result.Spec.EphemeralContainers {
+					names = append(names, container.Name)
+				}
+				Expect(names).To(Equal([]string{"debugger", "colleague", "mine"}))
+			})
+
+			It("should fail if the added container collides with a concurrent one", func() {
+				collision := t.newEphemeralContainer("colleague", "")
+				collision.Image = "busybox:1.27"
+				pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *collision)
+
+				_, err := t.clientset.UpdateEphemeralContainersForPod(context.Background(), pod)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("pod/testpod was modified concurrently and the ephemeral containers cannot be merged"))
+				Expect(err.Error()).To(ContainSubstring("containers colleague already exist in pod/testpod with a different spec"))
+			})
+		})
+	})
+
+	When("submitting in dry-run mode", func() {
+		It("should set dry-run in update options", func() {
+			opts := metav1.UpdateOptions{}
+			k8s.WithDryRun()(&opts)
+			Expect(opts.DryRun).To(Equal([]string{metav1.DryRunAll}))
+		})
+
+		It("should parse supported strategies", func() {
+			for _, strategy := range []k8s.DryRunStrategy{k8s.DryRunNone, k8s.DryRunClient, k8s.DryRunServer} {
+				parsed, err := k8s.ParseDryRunStrategy(string(strategy))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed).To(Equal(strategy))
+			}
+
+			_, err := k8s.ParseDryRunStrategy("all")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("watching pods", func() {
+		var fakeWatcher *watch.FakeWatcher
+
+		BeforeEach(func() {
+			fakeWatcher = watch.NewFake()
+			t.clientset.Interface.(*fake.Clientset).PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(fakeWatcher, nil))
+		})
+
+		It("should send pod events to handler", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := make(chan watch.EventType, 2)
+			done := make(chan error)
+			go func() {
+				done <- t.clientset.WatchPods(ctx, t.namespaces[0], metav1.ListOptions{}, "1", func(eventType watch.EventType, pod *corev1.Pod) error {
+					events <- eventType
+					return nil
+				})
+			}()
+
+			pod := t.newPod("watchpod", t.namespaces[0])
+			pod.ResourceVersion = "2"
+			fakeWatcher.Add(pod)
+			Eventually(events).Should(Receive(Equal(watch.Added)))
+
+			pod = pod.DeepCopy()
+			pod.ResourceVersion = "3"
+			fakeWatcher.Delete(pod)
+			Eventually(events).Should(Receive(Equal(watch.Deleted)))
+
+			cancel()
+			Eventually(done).Should(Receive(BeNil()))
+		})
+
+		It("should fail if the resource version is too old", func() {
+			go fakeWatcher.Error(&metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonExpired, Code: 410})
+
+			err := t.clientset.WatchPods(context.Background(), t.namespaces[0], metav1.ListOptions{}, "1", func(eventType watch.EventType, pod *corev1.Pod) error {
+				return nil
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("waiting for an ephemeral container", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			pod = t.newPod("waitpod", t.namespaces[0])
+		})
+
+		JustBeforeEach(func() {
+			_, err := t.clientset.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("that is running", func() {
+			BeforeEach(func() {
+				pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+					t.newContainerStatus("debugger", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}),
+				}
+			})
+
+			It("should return when waiting to be running", func() {
+				status, err := t.clientset.WaitForEphemeralContainer(context.Background(), pod.Namespace, pod.Name, "debugger", k8s.ConditionRunning)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.State.Running).ToNot(BeNil())
+			})
+
+			It("should time out when waiting to be terminated", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+				defer cancel()
+
+				_, err := t.clientset.WaitForEphemeralContainer(ctx, pod.Namespace, pod.Name, "debugger", k8s.ConditionTerminated)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("that failed to pull image", func() {
+			BeforeEach(func() {
+				pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+					t.newContainerStatus("debugger", corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}}),
+				}
+			})
+
+			It("should fail with the waiting reason", func() {
+				_, err := t.clientset.WaitForEphemeralContainer(context.Background(), pod.Namespace, pod.Name, "debugger", k8s.ConditionRunning)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("ErrImagePull"))
+			})
+		})
+
+		Context("that is still creating", func() {
+			BeforeEach(func() {
+				pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+					t.newContainerStatus("debugger", corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}),
+				}
+			})
+
+			It("should time out with the waiting reason", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+				defer cancel()
+
+				_, err := t.clientset.WaitForEphemeralContainer(ctx, pod.Namespace, pod.Name, "debugger", k8s.ConditionRunning)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("ContainerCreating"))
+			})
+
+			It("should stop on signals without a timeout", func() {
+				opts := &k8s.ContextOptions{}
+				Expect(opts.InitContext(nil)).To(Succeed())
+				defer opts.CancelContext()
+
+				done := make(chan error, 1)
+				go func() {
+					_, err := t.clientset.WaitForEphemeralContainer(opts, pod.Namespace, pod.Name, "debugger", k8s.ConditionRunning)
+					done <- err
+				}()
+				opts.SigChan <- syscall.SIGINT
+
+				var err error
+				Eventually(done, 5*time.Second).Should(Receive(&err))
+				Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			})
+		})
+
+		Context("that does not exist", func() {
+			It("should fail", func() {
+				_, err := t.clientset.WaitForEphemeralContainer(context.Background(), pod.Namespace, pod.Name, "not-a-container", k8s.ConditionRunning)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	When("selecting a running ephemeral container", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			pod = t.newPod("testpod", t.namespaces[0])
+			pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *t.newEphemeralContainer("another-debugger", ""))
+		})
+
+		It("should select the only running container", func() {
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+				t.newContainerStatus("debugger", corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}),
+				t.newContainerStatus("another-debugger", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}),
+			}
+
+			name, err := k8s.SelectRunningEphemeralContainer(pod)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("another-debugger"))
+			Expect(k8s.ValidateRunningEphemeralContainer(pod, name)).To(Succeed())
+			Expect(k8s.ValidateRunningEphemeralContainer(pod, "debugger")).ToNot(Succeed())
+		})
+
+		It("should fail if none is running", func() {
+			_, err := k8s.SelectRunningEphemeralContainer(pod)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if multiple are running", func() {
+			pod.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+				t.newContainerStatus("debugger", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}),
+				t.newContainerStatus("another-debugger", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}),
+			}
+
+			_, err := k8s.SelectRunningEphemeralContainer(pod)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("parsing a container condition", func() {
+		It("should accept supported conditions", func() {
+			for _, condition := range []k8s.ContainerCondition{k8s.ConditionRunning, k8s.ConditionTerminated} {
+				parsed, err := k8s.ParseContainerCondition(string(condition))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed).To(Equal(condition))
+			}
+		})
+
+		It("should fail otherwise", func() {
+			_, err := k8s.ParseContainerCondition("ready")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("constructing an ephemeral container", func() {
+		It("should set fields from options", func() {
+			container, err := k8s.NewEphemeralContainer(&k8s.EphemeralContainerOptions{
+				Name:            "dbg",
+				Image:           "busybox:1.28",
+				TargetContainer: "main",
+				Env:             []string{"KEY=VALUE", "EMPTY="},
+				Command:         []string{"sh", "-c", "sleep 3600"},
+				Stdin:           true,
+				TTY:             true,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(container.Name).To(Equal("dbg"))
+			Expect(container.Image).To(Equal("busybox:1.28"))
+			Expect(container.TargetContainerName).To(Equal("main"))
+			Expect(container.Env).To(Equal([]corev1.EnvVar{{Name: "KEY", Value: "VALUE"}, {Name: "EMPTY"}}))
+			Expect(container.Command).To(Equal([]string{"sh", "-c", "sleep 3600"}))
+			Expect(container.Stdin).To(BeTrue())
+			Expect(container.TTY).To(BeTrue())
+		})
+
+		It("should generate a name if unset", func() {
+			container, err := k8s.NewEphemeralContainer(&k8s.EphemeralContainerOptions{Image: "busybox:1.28"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(container.Name).To(HavePrefix(k8s.CONTAINER_NAME_PREFIX + "-"))
+		})
+
+		It("should fail with invalid environment variables", func() {
+			_, err := k8s.NewEphemeralContainer(&k8s.EphemeralContainerOptions{Image: "busybox:1.28", Env: []string{"INVALID"}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("adding an ephemeral container to a pod", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			pod = t.newPod("testpod", t.namespaces[0])
+		})
+
+		It("should append the container to a copy of the pod", func() {
+			container := t.newEphemeralContainer("another-debugger", "main")
+
+			result, err := k8s.AddEphemeralContainer(pod, container)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Spec.EphemeralContainers).To(HaveLen(2))
+			Expect(result.Spec.EphemeralContainers[1]).To(Equal(*container))
+			Expect(pod.Spec.EphemeralContainers).To(HaveLen(1))
+		})
+
+		It("should fail if the name is already used", func() {
+			_, err := k8s.AddEphemeralContainer(pod, t.newEphemeralContainer("main", ""))
+			Expect(err).To(HaveOccurred())
+
+			_, err = k8s.AddEphemeralContainer(pod, t.newEphemeralContainer("debugger", ""))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the target container does not exist", func() {
+			_, err := k8s.AddEphemeralContainer(pod, t.newEphemeralContainer("another-debugger", "not-a-container"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the image is unset", func() {
+			container := t.newEphemeralContainer("another-debugger", "")
+			container.Image = ""
+
+			_, err := k8s.AddEphemeralContainer(pod, container)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("matching the identity of the target container", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			pod = t.newPod("testpod", t.namespaces[0])
+			pod.Spec.SecurityContext = &corev1.PodSecurityContext{
+				RunAsUser:          ptr.To(int64(1000)),
+				RunAsGroup:         ptr.To(int64(3000)),
+				SupplementalGroups: []int64{4000},
+				FSGroup:            ptr.To(int64(2000)),
+			}
+			pod.Spec.Containers[0].SecurityContext = &corev1.SecurityContext{
+				RunAsUser:      ptr.To(int64(1001)),
+				RunAsNonRoot:   ptr.To(true),
+				SELinuxOptions: &corev1.SELinuxOptions{Level: "s0:c123,c456"},
+			}
+		})
+
+		It("should copy the user and group from the target container and the pod", func() {
+			container := t.newEphemeralContainer("another-debugger", "main")
+			container.SecurityContext = &corev1.SecurityContext{
+				RunAsUser:    ptr.To(int64(0)),
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"SYS_PTRACE"}},
+			}
+
+			Expect(k8s.MatchTargetIdentity(pod, container)).To(Succeed())
+			Expect(container.SecurityContext).To(Equal(&corev1.SecurityContext{
+				RunAsUser:      ptr.To(int64(1001)),
+				RunAsGroup:     ptr.To(int64(3000)),
+				RunAsNonRoot:   ptr.To(true),
+				SELinuxOptions: &corev1.SELinuxOptions{Level: "s0:c123,c456"},
+				Capabilities:   &corev1.Capabilities{Add: []corev1.Capability{"SYS_PTRACE"}},
+			}))
+
+			// The copies do not share pointers with the pod
+			*container.SecurityContext.RunAsUser = 0
+			Expect(*pod.Spec.Containers[0].SecurityContext.RunAsUser).To(Equal(int64(1001)))
+		})
+
+		It("should keep the fields set by neither the target nor the pod", func() {
+			pod.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsUser: ptr.To(int64(1000))}
+			pod.Spec.Containers[0].SecurityContext = nil
+
+			// e.g. from the built-in profile "restricted"
+			restricted := &corev1.SecurityContext{
+				RunAsNonRoot:             ptr.To(true),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				AllowPrivilegeEscalation: ptr.To(false),
+				SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			}
+			container := t.newEphemeralContainer("another-debugger", "main")
+			container.SecurityContext = restricted.DeepCopy()
+
+			Expect(k8s.MatchTargetIdentity(pod, container)).To(Succeed())
+
+			expected := restricted.DeepCopy()
+			expected.RunAsUser = ptr.To(int64(1000))
+			Expect(container.SecurityContext).To(Equal(expected))
+		})
+
+		It("should leave the fields unset if neither the target nor the pod sets them", func() {
+			pod.Spec.SecurityContext = nil
+			pod.Spec.Containers[0].SecurityContext = nil
+
+			container := t.newEphemeralContainer("another-debugger", "main")
+			Expect(k8s.MatchTargetIdentity(pod, container)).To(Succeed())
+			Expect(container.SecurityContext).To(BeNil())
+		})
+
+		It("should fail without a target container", func() {
+			Expect(k8s.MatchTargetIdentity(pod, t.newEphemeralContainer("another-debugger", ""))).ToNot(Succeed())
+			Expect(k8s.MatchTargetIdentity(pod, t.newEphemeralContainer("another-debugger", "not-a-container"))).ToNot(Succeed())
+		})
+	})
+
+	When("customizing an ephemeral container", func() {
+		It("should strategic-merge the custom spec", func() {
+			container := t.newEphemeralContainer("another-debugger", "main")
+			container.Env = []corev1.EnvVar{{Name: "DEBUG", Value: "true"}, {Name: "LEVEL", Value: "info"}}
+
+			custom, err := k8s.ParseCustomContainer([]byte(`
+env:
+  - name: LEVEL
+    value: debug
+volumeMounts:
+  - name: data
+    mountPath: /data
+securityContext:
+  capabilities:
+    add: ["SYS_PTRACE"]
+`))
+			Expect(err).ToNot(HaveOccurred())
+
+			merged, err := k8s.MergeCustomContainer(container, custom)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(merged.Name).To(Equal("another-debugger"))
+			Expect(merged.Image).To(Equal("busybox:1.28"))
+			Expect(merged.TargetContainerName).To(Equal("main"))
+			Expect(merged.Env).To(ConsistOf(corev1.EnvVar{Name: "DEBUG", Value: "true"}, corev1.EnvVar{Name: "LEVEL", Value: "debug"}))
+			Expect(merged.VolumeMounts).To(Equal([]corev1.VolumeMount{{Name: "data", MountPath: "/data"}}))
+			Expect(merged.SecurityContext.Capabilities.Add).To(Equal([]corev1.Capability{"SYS_PTRACE"}))
+			Expect(container.VolumeMounts).To(BeEmpty())
+		})
+
+		It("should parse a custom spec in JSON", func() {
+			custom, err := k8s.ParseCustomContainer([]byte(`{"workingDir": "/tmp", "tty": true}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(custom.WorkingDir).To(Equal("/tmp"))
+			Expect(custom.TTY).To(BeTrue())
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := k8s.ParseCustomContainer([]byte(`{"notAField": true}`))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject fields not supported by ephemeral containers", func() {
+			_, err := k8s.ParseCustomContainer([]byte(`
+name: renamed
+ports:
+  - containerPort: 8080
+readinessProbe:
+  tcpSocket:
+    port: 8080
+resources:
+  limits:
+    cpu: 100m
+lifecycle:
+  preStop:
+    exec:
+      command: ["sleep", "1"]
+`))
+			Expect(err).To(HaveOccurred())
+			for _, fieldName := range []string{"name", "ports", "readinessProbe", "resources", "lifecycle"} {
+				Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("%s: Forbidden", fieldName)))
+			}
+			Expect(err.Error()).ToNot(ContainSubstring("livenessProbe"))
+		})
+	})
+
+	When("adding an ephemeral container to pods in bulk", func() {
+		var pods []corev1.Pod
+
+		JustBeforeEach(func() {
+			var err error
+			pods, err = t.clientset.ListPods(context.Background(), "", metav1.ListOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pods).To(HaveLen(2))
+		})
+
+		It("should add to all pods", func() {
+			results := t.clientset.AddEphemeralContainerToPods(context.Background(), pods, func(pod *corev1.Pod) (*corev1.EphemeralContainer, error) {
+				return t.newEphemeralContainer("netshoot", "main"), nil
+			}, k8s.BulkOptions{Concurrency: 2, QPS: 100})
+
+			Expect(results).To(HaveLen(2))
+			for idx, result := range results {
+				Expect(result.Pod).To(Equal(pods[idx].Name))
+				Expect(result.Namespace).To(Equal(pods[idx].Namespace))
+				Expect(result.Result).To(Equal(k8s.BulkAdded))
+				Expect(result.Err).ToNot(HaveOccurred())
+			}
+
+			summary, failed := k8s.SummarizeBulkResults(results)
+			Expect(summary).To(Equal("2 added, 0 skipped, 0 failed"))
+			Expect(failed).To(BeZero())
+		})
+
+		It("should skip pods with the ephemeral container", func() {
+			results := t.clientset.AddEphemeralContainerToPods(context.Background(), pods, func(pod *corev1.Pod) (*corev1.EphemeralContainer, error) {
+				return t.newEphemeralContainer("debugger", ""), nil
+			}, k8s.BulkOptions{Concurrency: 1})
+
+			for _, result := range results {
+				Expect(result.Result).To(Equal(k8s.BulkSkipped))
+			}
+		})
+
+		It("should report failures per pod", func() {
+			results := t.clientset.AddEphemeralContainerToPods(context.Background(), pods, func(pod *corev1.Pod) (*corev1.EphemeralContainer, error) {
+				return t.newEphemeralContainer("netshoot", "not-a-container"), nil
+			}, k8s.BulkOptions{Concurrency: 2})
+
+			summary, failed := k8s.SummarizeBulkResults(results)
+			Expect(summary).To(Equal("0 added, 0 skipped, 2 failed"))
+			Expect(failed).To(Equal(2))
+			Expect(results[0].Err).To(HaveOccurred())
+		})
+
+		It("should not update in client dry-run mode", func() {
+			results := t.clientset.AddEphemeralContainerToPods(context.Background(), pods, func(pod *corev1.Pod) (*corev1.EphemeralContainer, error) {
+				return t.newEphemeralContainer("netshoot", ""), nil
+			}, k8s.BulkOptions{Concurrency: 2, DryRun: k8s.DryRunClient})
+			Expect(results[0].Result).To(Equal(k8s.BulkAdded))
+
+			pod, err := t.clientset.GetPod(context.Background(), pods[0].Namespace, pods[0].Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8s.FindEphemeralContainer(pod, "netshoot")).To(BeNil())
+		})
+
+		It("should filter pods by name pattern", func() {
+			Expect(k8s.IsNamePattern("web-*")).To(BeTrue())
+			Expect(k8s.IsNamePattern("web-0")).To(BeFalse())
+
+			Expect(k8s.ApplyPodFilter(pods, k8s.NamePatternFilter("test*"))).To(HaveLen(2))
+			Expect(k8s.ApplyPodFilter(pods, k8s.NamePatternFilter("web-*"))).To(BeEmpty())
+		})
+	})
+
+	When("parsing ephemeral containers from manifests", func() {
+		DescribeTable("should return containers", func(manifest string, expected []string) {
+			containers, err := k8s.ParseEphemeralContainers(strings.NewReader(manifest))
+			Expect(err).ToNot(HaveOccurred())
+
+			names := make([]string, 0)
+			for _, container := range containers {
+				names = append(names, container.Name)
+			}
+			Expect(names).To(Equal(expected))
+		},
+			Entry("with a bare container", "name: dbg\nimage: busybox:1.28\n", []string{"dbg"}),
+			Entry("with a list of containers", "- name: dbg\n  image: busybox:1.28\n- name: dbg-1\n  image: busybox:1.28\n", []string{"dbg", "dbg-1"}),
+			Entry("with a partial pod", "spec:\n  ephemeralContainers:\n  - name: dbg\n    image: busybox:1.28\n", []string{"dbg"}),
+			Entry("with a pod", "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  ephemeralContainers:\n  - name: dbg\n    image: busybox:1.28\n", []string{"dbg"}),
+			Entry("with multiple documents", "---\nname: dbg\nimage: busybox:1.28\n---\n- name: dbg-1\n  image: busybox:1.28\n---\n", []string{"dbg", "dbg-1"}),
+			Entry("with JSON", `{"name": "dbg", "image": "busybox:1.28"}
+{"spec": {"ephemeralContainers": [{"name": "dbg-1", "image": "busybox:1.28"}]}}`, []string{"dbg", "dbg-1"}),
+			Entry("with a JSON list", `[{"name": "dbg", "image": "busybox:1.28"}]`, []string{"dbg"}),
+		)
+
+		DescribeTable("should fail", func(manifest string) {
+			_, err := k8s.ParseEphemeralContainers(strings.NewReader(manifest))
+			Expect(err).To(HaveOccurred())
+		},
+			Entry("with unknown fields", "name: dbg\nimage: busybox:1.28\nunknown: field\n"),
+			Entry("with a missing name", "image: busybox:1.28\n"),
+			Entry("with an unsupported kind", "kind: Deployment\nspec: {}\n"),
+		)
+	})
+
+	When("sanitizing an edited pod", func() {
+		var original, edited *corev1.Pod
+
+		BeforeEach(func() {
+			original = t.newPod("testpod", t.namespaces[0])
+			original.Spec.EphemeralContainers = append(original.Spec.EphemeralContainers, *t.newEphemeralContainer("another-debugger", "main"))
+			edited = original.DeepCopy()
+		})
+
+		It("should return nil without changes", func() {
+			patch, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch).To(BeNil())
+		})
+
+		It("should return added containers after existing ones", func() {
+			edited.Spec.EphemeralContainers = append([]corev1.EphemeralContainer{*t.newEphemeralContainer("new-debugger", "")}, edited.Spec.EphemeralContainers...)
+
+			patch, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch.Spec.EphemeralContainers).To(HaveLen(3))
+			Expect(patch.Spec.EphemeralContainers[2].Name).To(Equal("new-debugger"))
+		})
+
+		It("should keep the original resourceVersion", func() {
+			original.ResourceVersion = "42"
+			edited.Spec.EphemeralContainers = append(edited.Spec.EphemeralContainers, *t.newEphemeralContainer("new-debugger", ""))
+
+			patch, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch.ResourceVersion).To(Equal("42"))
+		})
+
+		It("should keep the resourceVersion of a minified pod", func() {
+			original.ResourceVersion = "42"
+			minified := k8s.MinifyPod(original)
+			Expect(minified.ResourceVersion).To(Equal("42"))
+
+			edited := minified.DeepCopy()
+			edited.Spec.EphemeralContainers = append(edited.Spec.EphemeralContainers, *t.newEphemeralContainer("new-debugger", ""))
+
+			patch, err := k8s.SanitizeEditedPod(minified, edited)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch.ResourceVersion).To(Equal("42"))
+		})
+
+		It("should ignore reordering", func() {
+			containers := edited.Spec.EphemeralContainers
+			containers[0], containers[1] = containers[1], containers[0]
+
+			diff := k8s.DiffEphemeralContainers(original.Spec.EphemeralContainers, containers)
+			Expect(diff.Reordered).To(BeTrue())
+			Expect(diff.IsEmpty()).To(BeTrue())
+
+			patch, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch).To(BeNil())
+		})
+
+		It("should ignore fields defaulted by the API server", func() {
+			original.Spec.EphemeralContainers[0].ImagePullPolicy = corev1.PullIfNotPresent
+			original.Spec.EphemeralContainers[0].TerminationMessagePath = "/dev/termination-log"
+
+			patch, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(patch).To(BeNil())
+		})
+
+		It("should reject modified containers with field-level errors", func() {
+			edited.Spec.EphemeralContainers[0].Image = "busybox:1.27"
+			edited.Spec.EphemeralContainers[0].Command = []string{"sh"}
+
+			diff := k8s.DiffEphemeralContainers(original.Spec.EphemeralContainers, edited.Spec.EphemeralContainers)
+			Expect(diff.Modified).To(Equal([]string{"debugger"}))
+			Expect(diff.Errors).To(HaveLen(2))
+
+			_, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`spec.ephemeralContainers[debugger].image: Forbidden: existing ephemeral container may not be changed, was "busybox:1.28", now "busybox:1.27"`))
+			Expect(err.Error()).To(ContainSubstring(`spec.ephemeralContainers[debugger].command: Forbidden: existing ephemeral container may not be changed, was null, now ["sh"]`))
+		})
+
+		It("should reject removed containers", func() {
+			edited.Spec.EphemeralContainers = edited.Spec.EphemeralContainers[1:]
+
+			diff := k8s.DiffEphemeralContainers(original.Spec.EphemeralContainers, edited.Spec.EphemeralContainers)
+			Expect(diff.Removed).To(Equal([]string{"debugger"}))
+
+			_, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.ephemeralContainers[debugger]: Forbidden: existing ephemeral container may not be removed"))
+		})
+
+		It("should show added containers in unified diff", func() {
+			edited.Spec.EphemeralContainers = append(edited.Spec.EphemeralContainers, *t.newEphemeralContainer("new-debugger", ""))
+
+			patch, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).ToNot(HaveOccurred())
+
+			diff, err := k8s.UnifiedDiffEphemeralContainers(original, patch)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diff).To(HavePrefix("--- pod/testpod (original)\n+++ pod/testpod (edited)\n"))
+			Expect(diff).To(ContainSubstring("+  - image: busybox:1.28\n+    name: new-debugger\n"))
+			Expect(diff).ToNot(ContainSubstring("-  "))
+		})
+
+		It("should reject duplicate names", func() {
+			edited.Spec.EphemeralContainers = append(edited.Spec.EphemeralContainers, *t.newEphemeralContainer("new-debugger", ""), *t.newEphemeralContainer("new-debugger", ""))
+
+			_, err := k8s.SanitizeEditedPod(original, edited)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`spec.ephemeralContainers[new-debugger].name: Duplicate value: "new-debugger"`))
+		})
+	})
+
+	When("merging ephemeral containers into a pod", func() {
+		var pod *corev1.Pod
+
+		BeforeEach(func() {
+			pod = t.newPod("testpod", t.namespaces[0])
+			// Simulate server defaults
+			pod.Spec.EphemeralContainers[0].ImagePullPolicy = corev1.PullIfNotPresent
+			pod.Spec.EphemeralContainers[0].TerminationMessagePath = "/dev/termination-log"
+			pod.Spec.EphemeralContainers[0].TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		})
+
+		It("should add new containers and skip identical ones", func() {
+			containers := []corev1.EphemeralContainer{
+				*t.newEphemeralContainer("debugger", ""),
+				*t.newEphemeralContainer("another-debugger", "main"),
+			}
+
+			result, added, skipped, err := k8s.MergeEphemeralContainers(pod, containers)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(added).To(Equal([]string{"another-debugger"}))
+			Expect(skipped).To(Equal([]string{"debugger"}))
+			Expect(result.Spec.EphemeralContainers).To(HaveLen(2))
+		})
+
+		It("should fail if an existing container has a different spec", func() {
+			container := t.newEphemeralContainer("debugger", "")
+			container.Image = "busybox:1.27"
+
+			_, _, _, err := k8s.MergeEphemeralContainers(pod, []corev1.EphemeralContainer{*container})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if the name is used by a container", func() {
+			_, _, _, err := k8s.MergeEphemeralContainers(pod, []corev1.EphemeralContainer{*t.newEphemeralContainer("main", "")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("running preflight checks", func() {
+		var allowed map[string]bool
+
+		BeforeEach(func() {
+			fakeClientset := t.clientset.Interface.(*fake.Clientset)
+			fakeDiscovery := fakeClientset.Discovery().(*fakediscovery.FakeDiscovery)
+			fakeDiscovery.FakedServerVersion = &apiversion.Info{GitVersion: "v1.31.2"}
+			fakeDiscovery.Resources = []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{
+						{Name: "pods", Verbs: []string{"get", "list"}},
+						{Name: "pods/ephemeralcontainers", Verbs: []string{"get", "patch", "update"}},
+					},
+				},
+			}
+
+			allowed = map[string]bool{"get pods": true, "list pods": true, "update pods/ephemeralcontainers": true}
+			fakeClientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attrs := review.Spec.ResourceAttributes
+
+				resource := attrs.Resource
+				if len(attrs.Subresource) > 0 {
+					resource += "/" + attrs.Subresource
+				}
+				review.Status.Allowed = allowed[attrs.Verb+" "+resource]
+				return true, review, nil
+			})
+		})
+
+		JustBeforeEach(func() {
+			ns, err := t.clientset.CoreV1().Namespaces().Get(context.Background(), t.namespaces[0], metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			ns.Labels = map[string]string{k8s.PodSecurityEnforceLabel: "restricted"}
+			_, err = t.clientset.CoreV1().Namespaces().Update(context.Background(), ns, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should report the status of each check", func() {
+			results := t.clientset.RunPreflightChecks(context.Background(), t.namespaces[0])
+
+			statuses := map[string]k8s.CheckStatus{}
+			for _, result := range results {
+				statuses[result.Name] = result.Status
+			}
+			Expect(statuses).To(Equal(map[string]k8s.CheckStatus{
+				"Server version":                             k8s.CheckPass,
+				"Subresource pods/ephemeralcontainers":       k8s.CheckPass,
+				"Permission get pods":                        k8s.CheckPass,
+				"Permission list pods":                       k8s.CheckPass,
+				"Permission update pods/ephemeralcontainers": k8s.CheckPass,
+				"Permission patch pods/ephemeralcontainers":  k8s.CheckWarn,
+				"Permission create pods/attach":              k8s.CheckWarn,
+				"Permission get pods/log":                    k8s.CheckWarn,
+				"Pod Security":                               k8s.CheckWarn,
+			}))
+			Expect(k8s.HasFailedCheck(results)).To(BeFalse())
+		})
+
+		It("should fail if a required permission is denied", func() {
+			allowed["update pods/ephemeralcontainers"] = false
+
+			results := t.clientset.RunPreflightChecks(context.Background(), t.namespaces[0])
+			Expect(k8s.HasFailedCheck(results)).To(BeTrue())
+		})
+
+		It("should fail on an old server without the subresource", func() {
+			fakeDiscovery := t.clientset.Discovery().(*fakediscovery.FakeDiscovery)
+			fakeDiscovery.FakedServerVersion = &apiversion.Info{GitVersion: "v1.22.4"}
+			fakeDiscovery.Resources[0].APIResources = fakeDiscovery.Resources[0].APIResources[:1]
+
+			results := t.clientset.RunPreflightChecks(context.Background(), t.namespaces[0])
+			Expect(results[0].Status).To(Equal(k8s.CheckFail))
+			Expect(results[0].Message).To(ContainSubstring("older than v1.25.0"))
+			Expect(results[1].Status).To(Equal(k8s.CheckFail))
+		})
+	})
+
+	When("validating Pod Security", func() {
+		var pod *corev1.Pod
+		var baseline, restricted psaapi.LevelVersion
+
+		BeforeEach(func() {
+			pod = t.newPod("testpod", t.namespaces[0])
+			baseline = psaapi.LevelVersion{Level: psaapi.LevelBaseline, Version: psaapi.LatestVersion()}
+			restricted = psaapi.LevelVersion{Level: psaapi.LevelRestricted, Version: psaapi.LatestVersion()}
+		})
+
+		It("should get the level enforced in the namespace", func() {
+			lv, err := t.clientset.GetPodSecurityEnforcement(context.Background(), t.namespaces[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lv.Level).To(Equal(psaapi.LevelPrivileged))
+
+			ns := t.newNamespace(t.namespaces[0])
+			ns.Labels = map[string]string{k8s.PodSecurityEnforceLabel: "baseline", k8s.PodSecurityEnforceVersionLabel: "v1.30"}
+			_, err = t.clientset.CoreV1().Namespaces().Update(context.Background(), ns, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			lv, err = t.clientset.GetPodSecurityEnforcement(context.Background(), t.namespaces[0])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lv.String()).To(Equal("baseline:v1.30"))
+		})
+
+		It("should fail on invalid labels", func() {
+			ns := t.newNamespace(t.namespaces[0])
+			ns.Labels = map[string]string{k8s.PodSecurityEnforceLabel: "strict"}
+			_, err := t.clientset.CoreV1().Namespaces().Update(context.Background(), ns, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			lv, err := t.clientset.GetPodSecurityEnforcement(context.Background(), t.namespaces[0])
+			Expect(err).To(HaveOccurred())
+			Expect(lv.Level).To(Equal(psaapi.LevelRestricted))
+		})
+
+		It("should list the ephemeral containers added by a patch", func() {
+			patch := pod.DeepCopy()
+			patch.Spec.EphemeralContainers = append(patch.Spec.EphemeralContainers, *t.newEphemeralContainer("another-debugger", ""))
+
+			added := k8s.AddedEphemeralContainers(pod, patch)
+			Expect(added).To(HaveLen(1))
+			Expect(added[0].Name).To(Equal("another-debugger"))
+		})
+
+		It("should report violations per container", func() {
+			sniffer := t.newEphemeralContainer("sniffer", "main")
+			sniffer.SecurityContext = &corev1.SecurityContext{
+				Privileged:   ptr.To(true),
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "CHOWN"}},
+			}
+
+			violations, err := k8s.EvaluatePodSecurity(baseline, pod, []corev1.EphemeralContainer{*sniffer})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(ConsistOf(
+				k8s.PodSecurityViolation{Container: "sniffer", Reason: "privileged", Detail: `container "sniffer" must not set securityContext.privileged=true`},
+				k8s.PodSecurityViolation{Container: "sniffer", Reason: "non-default capabilities", Detail: `container "sniffer" must not include "NET_ADMIN" in securityContext.capabilities.add`},
+			))
+		})
+
+		It("should not report violations if privileged", func() {
+			sniffer := t.newEphemeralContainer("sniffer", "main")
+			sniffer.SecurityContext = &corev1.SecurityContext{Privileged: ptr.To(true)}
+
+			violations, err := k8s.EvaluatePodSecurity(psaapi.LevelVersion{Level: psaapi.LevelPrivileged}, pod, []corev1.EphemeralContainer{*sniffer})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(BeEmpty())
+		})
+
+		It("should adjust a container to comply with baseline", func() {
+			sniffer := t.newEphemeralContainer("sniffer", "main")
+			sniffer.SecurityContext = &corev1.SecurityContext{
+				Privileged:   ptr.To(true),
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "CHOWN"}},
+			}
+
+			compliant, err := k8s.CompliantEphemeralContainer(baseline, pod, sniffer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compliant.SecurityContext.Privileged).To(BeNil())
+			Expect(compliant.SecurityContext.Capabilities.Add).To(Equal([]corev1.Capability{"CHOWN"}))
+			Expect(sniffer.SecurityContext.Privileged).To(Equal(ptr.To(true)))
+		})
+
+		It("should adjust a container to comply with restricted", func() {
+			pod.Spec.SecurityContext = &corev1.PodSecurityContext{
+				RunAsNonRoot:   ptr.To(true),
+				RunAsUser:      ptr.To(int64(1000)),
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			}
+			restrictedContext := &corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr.To(false),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			}
+			pod.Spec.Containers[0].SecurityContext = restrictedContext
+			pod.Spec.EphemeralContainers[0].SecurityContext = restrictedContext
+
+			debugger := t.newEphemeralContainer("another-debugger", "main")
+			debugger.SecurityContext = &corev1.SecurityContext{RunAsUser: ptr.To(int64(0))}
+
+			violations, err := k8s.EvaluatePodSecurity(restricted, pod, []corev1.EphemeralContainer{*debugger})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).ToNot(BeEmpty())
+			for _, violation := range violations {
+				Expect(violation.Container).To(Equal("another-debugger"))
+			}
+
+			compliant, err := k8s.CompliantEphemeralContainer(restricted, pod, debugger)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(compliant.SecurityContext).To(Equal(&corev1.SecurityContext{
+				AllowPrivilegeEscalation: ptr.To(false),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				RunAsNonRoot:             ptr.To(true),
+				RunAsUser:                ptr.To(k8s.NOBODY_UID),
+			}))
+		})
+
+		It("should report violations of the pod itself without a compliant variant", func() {
+			pod.Spec.HostNetwork = true
+
+			violations, err := k8s.EvaluatePodSecurity(baseline, pod, []corev1.EphemeralContainer{*t.newEphemeralContainer("another-debugger", "")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(HaveLen(1))
+			Expect(violations[0].Container).To(BeEmpty())
+			Expect(violations[0].Reason).To(Equal("host namespaces"))
+
+			_, err = k8s.CompliantEphemeralContainer(baseline, pod, t.newEphemeralContainer("another-debugger", ""))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("parsing a resource reference from arguments", func() {
+		DescribeTable("should resolve the kind", func(args []string, expected *k8s.ResourceRef) {
+			ref, err := k8s.GetResourceRefFromArgs(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ref).To(Equal(expected))
+		},
+			Entry("with a pod name", []string{"web"}, &k8s.ResourceRef{Kind: k8s.KindPod, Name: "web"}),
+			Entry("with pod/name", []string{"pod/web"}, &k8s.ResourceRef{Kind: k8s.KindPod, Name: "web"}),
+			Entry("with pods name", []string{"pods", "web"}, &k8s.ResourceRef{Kind: k8s.KindPod, Name: "web"}),
+			Entry("with deploy/name", []string{"deploy/web"}, &k8s.ResourceRef{Kind: k8s.KindDeployment, Name: "web"}),
+			Entry("with sts name", []string{"sts", "db"}, &k8s.ResourceRef{Kind: k8s.KindStatefulSet, Name: "db"}),
+			Entry("with DaemonSet/name", []string{"DaemonSet/agent"}, &k8s.ResourceRef{Kind: k8s.KindDaemonSet, Name: "agent"}),
+			Entry("with jobs/name", []string{"jobs/backup"}, &k8s.ResourceRef{Kind: k8s.KindJob, Name: "backup"}),
+			Entry("with rs/name", []string{"rs/web-abc"}, &k8s.ResourceRef{Kind: k8s.KindReplicaSet, Name: "web-abc"}),
+		)
+
+		DescribeTable("should fail", func(args []string) {
+			_, err := k8s.GetResourceRefFromArgs(args)
+			Expect(err).To(HaveOccurred())
+		},
+			Entry("with an unsupported kind", []string{"svc/web"}),
+			Entry("with too many parts", []string{"deploy/web/extra"}),
+			Entry("with no arguments", []string{}),
+			Entry("with too many arguments", []string{"deploy", "web", "extra"}),
+		)
+	})
+
+	When("getting pods for a workload", func() {
+		JustBeforeEach(func() {
+			ns := t.namespaces[0]
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: ns, UID: "deploy-uid"},
+				Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			}
+			rs := &appsv1.ReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: ns, UID: "rs-uid", Labels: map[string]string{"app": "web"}, OwnerReferences: t.newControllerRef("Deployment", deploy.ObjectMeta)},
+				Spec:       appsv1.ReplicaSetSpec{Selector: deploy.Spec.Selector},
+			}
+			sts := &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: ns, UID: "sts-uid"},
+				Spec:       appsv1.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}},
+			}
+
+			_, err := t.clientset.AppsV1().Deployments(ns).Create(context.Background(), deploy, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = t.clientset.AppsV1().ReplicaSets(ns).Create(context.Background(), rs, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = t.clientset.AppsV1().StatefulSets(ns).Create(context.Background(), sts, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			pods := []*corev1.Pod{
+				t.newPod("web-abc-1", ns),
+				t.newPod("web-abc-2", ns),
+				t.newPod("web-orphan", ns), // Matches the selector without an owner
+				t.newPod("db-0", ns),
+			}
+			pods[0].Labels, pods[0].OwnerReferences = map[string]string{"app": "web"}, t.newControllerRef("ReplicaSet", rs.ObjectMeta)
+			pods[1].Labels, pods[1].OwnerReferences = map[string]string{"app": "web"}, t.newControllerRef("ReplicaSet", rs.ObjectMeta)
+			pods[2].Labels = map[string]string{"app": "web"}
+			pods[3].Labels, pods[3].OwnerReferences = map[string]string{"app": "db"}, t.newControllerRef("StatefulSet", sts.ObjectMeta)
+
+			for _, pod := range pods {
+				_, err = t.clientset.CoreV1().Pods(ns).Create(context.Background(), pod, metav1.CreateOptions{})
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		DescribeTable("should return the controlled pods", func(ref *k8s.ResourceRef, expected []string) {
+			pods, err := t.clientset.GetPodsForWorkload(context.Background(), t.namespaces[0], ref)
+			Expect(err).ToNot(HaveOccurred())
+
+			names := []string{}
+			for _, pod := range pods {
+				names = append(names, pod.Name)
+				Expect(pod.APIVersion).To(Equal("v1"))
+				Expect(pod.Kind).To(Equal("Pod"))
+			}
+			Expect(names).To(ConsistOf(expected))
+		},
+			Entry("for a deployment via its replicasets", &k8s.ResourceRef{Kind: k8s.KindDeployment, Name: "web"}, []string{"web-abc-1", "web-abc-2"}),
+			Entry("for a replicaset", &k8s.ResourceRef{Kind: k8s.KindReplicaSet, Name: "web-abc"}, []string{"web-abc-1", "web-abc-2"}),
+			Entry("for a statefulset", &k8s.ResourceRef{Kind: k8s.KindStatefulSet, Name: "db"}, []string{"db-0"}),
+		)
+
+		It("should fail if the workload does not exist", func() {
+			_, err := t.clientset.GetPodsForWorkload(context.Background(), t.namespaces[0], &k8s.ResourceRef{Kind: k8s.KindDaemonSet, Name: "agent"})
+			Expect(err).To(HaveOccurred())
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	When("selecting the newest ready pod", func() {
+		var pods []corev1.Pod
+
+		BeforeEach(func() {
+			pods = nil
+			now := time.Now()
+			for idx, name := range []string{"old-ready", "new-ready", "newest-not-ready"} {
+				pod := t.newPod(name, t.namespaces[0])
+				pod.CreationTimestamp = metav1.NewTime(now.Add(time.Duration(idx) * time.Minute))
+				pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+				pods = append(pods, *pod)
+			}
+			pods[2].Status.Conditions[0].Status = corev1.ConditionFalse
+		})
+
+		It("should prefer a ready pod", func() {
+			pod, err := k8s.SelectNewestReadyPod(pods)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pod.Name).To(Equal("new-ready"))
+		})
+
+		It("should fall back to the newest pod if none is ready", func() {
+			for idx := range pods {
+				pods[idx].Status.Conditions = nil
+			}
+
+			pod, err := k8s.SelectNewestReadyPod(pods)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pod.Name).To(Equal("newest-not-ready"))
+		})
+
+		It("should fail without pods", func() {
+			_, err := k8s.SelectNewestReadyPod(nil)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 })
 
@@ -187,6 +1387,50 @@ func (t *test) newPod(name, namespace string) *corev1.Pod {
 	}
 }
 
+func (t *test) newPodList(continueToken string, names ...string) *corev1.PodList {
+	podList := &corev1.PodList{
+		ListMeta: metav1.ListMeta{
+			Continue: continueToken,
+		},
+	}
+
+	for _, name := range names {
+		podList.Items = append(podList.Items, *t.newPod(name, t.namespaces[0]))
+	}
+
+	return podList
+}
+
+func (t *test) newControllerRef(kind string, owner metav1.ObjectMeta) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{
+		{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       owner.Name,
+			UID:        owner.UID,
+			Controller: &controller,
+		},
+	}
+}
+
+func (t *test) newEphemeralContainer(name, target string) *corev1.EphemeralContainer {
+	return &corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:  name,
+			Image: "busybox:1.28",
+		},
+		TargetContainerName: target,
+	}
+}
+
+func (t *test) newContainerStatus(name string, state corev1.ContainerState) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  name,
+		State: state,
+	}
+}
+
 func (t *test) expectKubeConfig(kubeConfig *k8s.KubeConfig) {
 	Expect(kubeConfig).ToNot(BeNil())
 	Expect(kubeConfig.ConfigFlags).ToNot(BeNil())
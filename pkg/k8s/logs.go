@@ -0,0 +1,130 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Represent an ephemeral container to stream logs from
+type LogTarget struct {
+	Namespace string
+	Pod       string
+	Container string
+}
+
+// Format as namespace/pod/container
+func (target LogTarget) String() string {
+	return fmt.Sprintf("%s/%s/%s", target.Namespace, target.Pod, target.Container)
+}
+
+// Get the ephemeral containers of pods to stream logs from
+// If containerName is set, only ephemeral containers with the name are included
+func GetLogTargets(pods []corev1.Pod, containerName string) (targets []LogTarget) {
+	for _, pod := range pods {
+		for _, container := range pod.Spec.EphemeralContainers {
+			if len(containerName) > 0 && container.Name != containerName {
+				continue
+			}
+			targets = append(targets, LogTarget{
+				Namespace: pod.Namespace,
+				Pod:       pod.Name,
+				Container: container.Name,
+			})
+		}
+	}
+	return targets
+}
+
+// Stream logs from targets concurrently to a writer
+// Lines are written as a whole. If prefix is true, each line is prefixed with [namespace/pod/container]
+func (client *KubeClientset) StreamLogs(ctx context.Context, targets []LogTarget, opts *corev1.PodLogOptions, w io.Writer, prefix bool) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := make([]error, len(targets))
+
+	for idx, target := range targets {
+		wg.Add(1)
+		go func(idx int, target LogTarget) {
+			defer wg.Done()
+
+			if err := client.streamLogs(ctx, target, opts, w, &mu, prefix); err != nil {
+				errs[idx] = errors.Join(fmt.Errorf("failed to stream logs from %s", target), err)
+			}
+		}(idx, target)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Stream logs from a single target
+func (client *KubeClientset) streamLogs(ctx context.Context, target LogTarget, opts *corev1.PodLogOptions, w io.Writer, mu *sync.Mutex, prefix bool) (err error) {
+	logOpts := opts.DeepCopy()
+	logOpts.Container = target.Container
+
+	stream, err := client.CoreV1().Pods(target.Namespace).GetLogs(target.Pod, logOpts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, stream.Close())
+	}()
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+
+			mu.Lock()
+			if prefix {
+				_, err = fmt.Fprintf(w, "[%s] %s", target, line)
+			} else {
+				_, err = w.Write(line)
+			}
+			mu.Unlock()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+			return readErr
+		}
+	}
+}
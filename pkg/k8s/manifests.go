@@ -0,0 +1,193 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	decoderBufferSize int = 4096
+
+	// Defaults set by the API server for ephemeral containers
+	defaultTerminationMessagePath string = "/dev/termination-log"
+)
+
+// Represent the fields to determine the kind of a manifest document
+type manifestHeader struct {
+	Kind string          `json:"kind,omitempty"`
+	Spec json.RawMessage `json:"spec,omitempty"`
+}
+
+// Parse ephemeral containers from YAML or JSON manifests
+// A manifest can contain multiple documents. Each document is one of:
+// * An ephemeral container
+// * A list of ephemeral containers
+// * A (partial) pod with spec.ephemeralContainers
+func ParseEphemeralContainers(r io.Reader) ([]corev1.EphemeralContainer, error) {
+	var containers []corev1.EphemeralContainer
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, decoderBufferSize)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errors.Join(errors.New("failed to decode manifest"), err)
+		}
+
+		raw = bytes.TrimSpace(raw)
+		// Skip empty documents
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+
+		parsed, err := parseManifestDocument(raw)
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, parsed...)
+	}
+
+	for _, container := range containers {
+		if len(container.Name) == 0 {
+			return nil, errors.New("ephemeral containers in manifests must have a name")
+		}
+	}
+
+	return containers, nil
+}
+
+// Parse a single JSON document into ephemeral containers
+func parseManifestDocument(raw json.RawMessage) ([]corev1.EphemeralContainer, error) {
+	// A list of ephemeral containers
+	if raw[0] == '[' {
+		var containers []corev1.EphemeralContainer
+		if err := yaml.UnmarshalStrict(raw, &containers); err != nil {
+			return nil, errors.Join(errors.New("failed to parse list of ephemeral containers"), err)
+		}
+		return containers, nil
+	}
+
+	header := &manifestHeader{}
+	if err := json.Unmarshal(raw, header); err != nil {
+		return nil, errors.Join(errors.New("failed to parse manifest"), err)
+	}
+
+	// A (partial) pod
+	if header.Kind == "Pod" || (len(header.Kind) == 0 && len(header.Spec) > 0) {
+		pod := &corev1.Pod{}
+		if err := yaml.UnmarshalStrict(raw, pod); err != nil {
+			return nil, errors.Join(errors.New("failed to parse pod"), err)
+		}
+		return pod.Spec.EphemeralContainers, nil
+	}
+
+	if len(header.Kind) > 0 {
+		return nil, fmt.Errorf("unsupported kind %s. Expected an ephemeral container, a list of ephemeral containers or a pod", header.Kind)
+	}
+
+	// A bare ephemeral container
+	container := corev1.EphemeralContainer{}
+	if err := yaml.UnmarshalStrict(raw, &container); err != nil {
+		return nil, errors.Join(errors.New("failed to parse ephemeral container"), err)
+	}
+	return []corev1.EphemeralContainer{container}, nil
+}
+
+// Merge ephemeral containers into a copy of the pod
+// Containers that already exist with an identical spec are skipped. Containers with a conflicting name are rejected.
+func MergeEphemeralContainers(pod *corev1.Pod, containers []corev1.EphemeralContainer) (result *corev1.Pod, added []string, skipped []string, err error) {
+	result = pod.DeepCopy()
+
+	var conflicts []string
+	for _, container := range containers {
+		existing := FindEphemeralContainer(result, container.Name)
+		switch {
+		case existing != nil && IsSameEphemeralContainer(*existing, container):
+			skipped = append(skipped, container.Name)
+		case existing != nil || HasContainer(result, container.Name):
+			conflicts = append(conflicts, container.Name)
+		default:
+			result.Spec.EphemeralContainers = append(result.Spec.EphemeralContainers, *container.DeepCopy())
+			added = append(added, container.Name)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, nil, nil, fmt.Errorf("containers %s already exist in pod/%s with a different spec", strings.Join(conflicts, ","), pod.Name)
+	}
+
+	return result, added, skipped, nil
+}
+
+// Check if 2 ephemeral containers have the same spec, ignoring fields defaulted by the API server if unset
+func IsSameEphemeralContainer(existing, other corev1.EphemeralContainer) bool {
+	return equality.Semantic.DeepEqual(withEphemeralContainerDefaults(existing), withEphemeralContainerDefaults(other))
+}
+
+// Get a copy of the ephemeral container with API server defaults applied
+// See: https://github.com/kubernetes/kubernetes/blob/master/pkg/apis/core/v1/defaults.go
+func withEphemeralContainerDefaults(container corev1.EphemeralContainer) *corev1.EphemeralContainer {
+	result := container.DeepCopy()
+
+	if len(result.TerminationMessagePath) == 0 {
+		result.TerminationMessagePath = defaultTerminationMessagePath
+	}
+
+	if len(result.TerminationMessagePolicy) == 0 {
+		result.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	}
+
+	if len(result.ImagePullPolicy) == 0 {
+		result.ImagePullPolicy = defaultImagePullPolicy(result.Image)
+	}
+
+	return result
+}
+
+// Image pull policy defaults to Always for image with tag "latest" or without tag. Otherwise, IfNotPresent
+func defaultImagePullPolicy(image string) corev1.PullPolicy {
+	// Image referenced by digest
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+
+	lastSegment := image[strings.LastIndex(image, "/")+1:]
+	_, tag, found := strings.Cut(lastSegment, ":")
+	if !found || tag == "latest" {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
@@ -26,29 +26,85 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 )
 
 type PodFilterFn func(pod corev1.Pod) bool
 
+// Option to customize update requests
+type UpdateOption func(opts *metav1.UpdateOptions)
+
+// Strategy for dry-run requests
+type DryRunStrategy string
+
+const (
+	DryRunNone   DryRunStrategy = "none"
+	DryRunClient DryRunStrategy = "client" // Only build the request without sending it
+	DryRunServer DryRunStrategy = "server" // Send the request without persisting the result
+)
+
+const (
+	// Default number of pods to retrieve per list request
+	DEFAULT_CHUNK_SIZE int64 = 500
+
+	// Maximum number of times to restart a chunked list if the continue token expires
+	maxListRestarts int = 3
+)
+
 type KubeClientset struct {
 	kubernetes.Interface
+	// REST config for streaming requests (e.g. attach, exec)
+	Config *rest.Config
 }
 
 // List pods by filters in the specified namespace
 // If namespace is empty (i.e. ""), list in all namespaces
-func (client *KubeClientset) ListPods(ctx context.Context, namespace string, filters ...PodFilterFn) ([]corev1.Pod, error) {
-	podList, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, err
+// Selectors in list options are evaluated by the server before filters are applied
+// If a limit is set in list options, pods are listed in chunks and filters are applied per chunk
+// The list is restarted if the continue token expires
+func (client *KubeClientset) ListPods(ctx context.Context, namespace string, opts metav1.ListOptions, filters ...PodFilterFn) ([]corev1.Pod, error) {
+	pods, _, err := client.ListPodsWithResourceVersion(ctx, namespace, opts, filters...)
+	return pods, err
+}
+
+// Same as ListPods, but also return the resource version of the list (e.g. to start a watch from)
+func (client *KubeClientset) ListPodsWithResourceVersion(ctx context.Context, namespace string, opts metav1.ListOptions, filters ...PodFilterFn) ([]corev1.Pod, string, error) {
+	var result []corev1.Pod
+	var resourceVersion string
+
+	opts.Continue = ""
+	for restarts := 0; ; {
+		podList, err := client.CoreV1().Pods(namespace).List(ctx, opts)
+		if err != nil {
+			// Continue token expired (i.e. 410 Gone): Restart from the beginning
+			if len(opts.Continue) > 0 && apierrors.IsResourceExpired(err) && restarts < maxListRestarts {
+				restarts++
+				result, opts.Continue = nil, ""
+				continue
+			}
+			return nil, "", err
+		}
+
+		// All chunks are served from the snapshot of the first one
+		if len(opts.Continue) == 0 {
+			resourceVersion = podList.ResourceVersion
+		}
+
+		result = append(result, ApplyPodFilter(podList.Items, filters...)...)
+
+		if len(podList.Continue) == 0 {
+			return result, resourceVersion, nil
+		}
+		opts.Continue = podList.Continue
 	}
-	return ApplyPodFilter(podList.Items, filters...), nil
 }
 
 // Get pod by name in a specific namespace
@@ -61,8 +117,77 @@ func (client *KubeClientset) GetPod(ctx context.Context, namespace, name string)
 }
 
 // Update pod's ephemeralContainer subresource
-func (client *KubeClientset) UpdateEphemeralContainersForPod(ctx context.Context, pod *corev1.Pod) (*corev1.Pod, error) {
-	return client.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(ctx, pod.Name, pod, metav1.UpdateOptions{})
+// The pod's resourceVersion (if set) guards against concurrent changes. On conflict, the latest pod is fetched
+// and only the ephemeral containers that it does not have yet are re-applied on top of its ephemeral containers
+func (client *KubeClientset) UpdateEphemeralContainersForPod(ctx context.Context, pod *corev1.Pod, opts ...UpdateOption) (*corev1.Pod, error) {
+	updateOpts := metav1.UpdateOptions{}
+	for _, opt := range opts {
+		opt(&updateOpts)
+	}
+
+	var result *corev1.Pod
+	attempt := pod
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() (err error) {
+		if attempt == nil {
+			var latest *corev1.Pod
+			if latest, attempt, err = client.rebaseEphemeralContainers(ctx, pod); err != nil {
+				return err
+			}
+
+			// The latest pod already has all ephemeral containers
+			if attempt == nil {
+				result = latest
+				return nil
+			}
+		}
+
+		result, err = client.CoreV1().Pods(attempt.Namespace).UpdateEphemeralContainers(ctx, attempt.Name, attempt, updateOpts)
+		if apierrors.IsConflict(err) {
+			attempt = nil
+		}
+		return err
+	})
+
+	return result, err
+}
+
+// Merge the ephemeral containers of the pod into the latest pod from the API server
+// Return the latest pod and the patch to submit, which is nil if nothing changes
+func (client *KubeClientset) rebaseEphemeralContainers(ctx context.Context, pod *corev1.Pod) (*corev1.Pod, *corev1.Pod, error) {
+	latest, err := client.GetPod(ctx, pod.Namespace, pod.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged, _, _, err := MergeEphemeralContainers(latest, pod.Spec.EphemeralContainers)
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("pod/%s was modified concurrently and the ephemeral containers cannot be merged", pod.Name), err)
+	}
+
+	patch, err := SanitizeEditedPod(latest, merged)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return latest, patch, nil
+}
+
+// Submit the update request in server dry-run mode
+// Admission (e.g. webhooks and PodSecurity) still evaluates the request, but the result is not persisted
+func WithDryRun() UpdateOption {
+	return func(opts *metav1.UpdateOptions) {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+}
+
+// Parse a dry-run strategy from string
+func ParseDryRunStrategy(strategy string) (DryRunStrategy, error) {
+	switch s := DryRunStrategy(strategy); s {
+	case DryRunNone, DryRunClient, DryRunServer:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported dry-run strategy %q. One of: %s, %s, %s", strategy, DryRunNone, DryRunClient, DryRunServer)
+	}
 }
 
 // Explicitly set GVK for Pod
@@ -87,27 +212,43 @@ func SanitizeEditedPod(original, edited *corev1.Pod) (*corev1.Pod, error) {
 		return nil, fmt.Errorf("pod's namespace cannot be changed. Expected %s but got %s", original.Namespace, edited.Namespace)
 	}
 
-	// Nothing changes in spec.ephemeralContainers
-	if cmp.Equal(original.Spec.EphemeralContainers, edited.Spec.EphemeralContainers) {
+	diff := DiffEphemeralContainers(original.Spec.EphemeralContainers, edited.Spec.EphemeralContainers)
+	if err := diff.Errors.ToAggregate(); err != nil {
+		return nil, errors.Join(fmt.Errorf("illegal changes to ephemeral containers in pod/%s", original.Name), err)
+	}
+
+	// Nothing changes in spec.ephemeralContainers, ignoring order
+	if diff.IsEmpty() {
 		return nil, nil
 	}
 
-	return &corev1.Pod{
+	// Existing ephemeral containers are kept in original order, followed by new ones
+	containers := original.Spec.DeepCopy().EphemeralContainers
+	for _, name := range diff.Added {
+		containers = append(containers, *FindEphemeralContainer(edited, name).DeepCopy())
+	}
+
+	// Keep resourceVersion so that the update fails on conflict rather than overwriting concurrent changes
+	return setGVK(&corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      original.Name,
-			Namespace: original.Namespace,
+			Name:            original.Name,
+			Namespace:       original.Namespace,
+			ResourceVersion: original.ResourceVersion,
 		},
 		Spec: corev1.PodSpec{
-			EphemeralContainers: edited.Spec.DeepCopy().EphemeralContainers,
+			EphemeralContainers: containers,
 		},
-	}, nil
+	}), nil
 }
 
+// Keep only the information necessary for editing ephemeral containers
+// The resourceVersion is kept so that updates built from the minified pod still fail on conflict
 func MinifyPod(pod *corev1.Pod) *corev1.Pod {
 	result := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      pod.Name,
-			Namespace: pod.Namespace,
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			ResourceVersion: pod.ResourceVersion,
 		},
 		Spec: corev1.PodSpec{
 			EphemeralContainers: pod.Spec.DeepCopy().EphemeralContainers,
@@ -118,36 +259,27 @@ func MinifyPod(pod *corev1.Pod) *corev1.Pod {
 }
 
 // Apply filters (if any) to a list of pods
+// A pod is included only if it satisfies all filters
 func ApplyPodFilter(pods []corev1.Pod, filters ...PodFilterFn) (result []corev1.Pod) {
 	if len(filters) == 0 {
 		return pods
 	}
 
 	for _, pod := range pods {
-		for _, filter := range filters {
-			if filter(pod) {
-				result = append(result, pod)
-			}
+		if matchPodFilters(pod, filters...) {
+			result = append(result, pod)
 		}
 	}
 
 	return result
 }
 
-// Get pod name from CLI arguments
-func GetPodNameFromArgs(args []string) (string, error) {
-	switch len(args) {
-	case 1:
-		parts := strings.Split(args[0], "/")
-		if len(parts) > 2 {
-			return "", errors.New("single argument must have format: \"pod/pod-name\"")
-		} else if len(parts) == 1 { // Assume pod-name
-			return parts[0], nil
+// Check if a pod satisfies all filters
+func matchPodFilters(pod corev1.Pod, filters ...PodFilterFn) bool {
+	for _, filter := range filters {
+		if !filter(pod) {
+			return false
 		}
-		return parts[1], nil // pod/pod-name
-	case 2:
-		return args[1], nil // pod(s) pod-name
-	default:
-		return "", errors.New("invalid number of arguments. Expect 1 or 2 arguments: \"pod/pod-name\", or \"pod pod-name\"")
 	}
+	return true
 }
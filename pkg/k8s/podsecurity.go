@@ -0,0 +1,244 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	psaapi "k8s.io/pod-security-admission/api"
+	"k8s.io/pod-security-admission/policy"
+	"k8s.io/utils/ptr"
+)
+
+// A Pod Security violation found before submitting ephemeral containers
+type PodSecurityViolation struct {
+	Container string // Empty if the violation is caused by the pod itself (e.g. host namespaces)
+	Reason    string // The forbidden field (e.g. "allowPrivilegeEscalation != false")
+	Detail    string // The forbidden values (if any)
+}
+
+func (v PodSecurityViolation) String() string {
+	subject := "pod"
+	if len(v.Container) > 0 {
+		subject = fmt.Sprintf("container %s", v.Container)
+	}
+
+	if len(v.Detail) == 0 {
+		return fmt.Sprintf("%s: %s", subject, v.Reason)
+	}
+	return fmt.Sprintf("%s: %s (%s)", subject, v.Reason, v.Detail)
+}
+
+const (
+	// UID of the user "nobody", used to run adjusted containers as non-root
+	NOBODY_UID int64 = 65534
+)
+
+var (
+	// Capabilities allowed to be added by the baseline policy
+	// See: https://kubernetes.io/docs/concepts/security/pod-security-standards/#baseline
+	baselineCapabilities = []corev1.Capability{
+		"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD",
+		"NET_BIND_SERVICE", "SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+	}
+
+	// Capabilities allowed to be added by the restricted policy
+	restrictedCapabilities = []corev1.Capability{"NET_BIND_SERVICE"}
+
+	// SELinux types allowed by the baseline policy
+	baselineSELinuxTypes = []string{"", "container_t", "container_init_t", "container_kvm_t", "container_engine_t"}
+
+	// The upstream evaluator is built once with the default checks
+	getPodSecurityEvaluator = sync.OnceValues(func() (policy.Evaluator, error) {
+		return policy.NewEvaluator(policy.DefaultChecks())
+	})
+)
+
+// Get the Pod Security level and version enforced in the namespace
+// Namespaces without the enforce label are privileged
+func (client *KubeClientset) GetPodSecurityEnforcement(ctx context.Context, namespace string) (psaapi.LevelVersion, error) {
+	defaults := psaapi.LevelVersion{Level: psaapi.LevelPrivileged, Version: psaapi.LatestVersion()}
+
+	ns, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return defaults, err
+	}
+
+	// Invalid labels are evaluated as restricted, which is also what the admission controller does
+	nsPolicy, errs := psaapi.PolicyToEvaluate(ns.Labels, psaapi.Policy{Enforce: defaults, Audit: defaults, Warn: defaults})
+	if err := errs.ToAggregate(); err != nil {
+		return nsPolicy.Enforce, errors.Join(fmt.Errorf("invalid Pod Security labels on namespace %s", namespace), err)
+	}
+
+	return nsPolicy.Enforce, nil
+}
+
+// Get the ephemeral containers in the patch that are not in the original pod yet
+func AddedEphemeralContainers(original, patch *corev1.Pod) []corev1.EphemeralContainer {
+	var added []corev1.EphemeralContainer
+	for _, name := range DiffEphemeralContainers(original.Spec.EphemeralContainers, patch.Spec.EphemeralContainers).Added {
+		added = append(added, *FindEphemeralContainer(patch, name).DeepCopy())
+	}
+	return added
+}
+
+// Evaluate the pod with the new ephemeral containers against the Pod Security level and version
+// Violations of the pod itself are reported once. Violations of each new ephemeral container are reported separately
+func EvaluatePodSecurity(lv psaapi.LevelVersion, pod *corev1.Pod, containers []corev1.EphemeralContainer) ([]PodSecurityViolation, error) {
+	if lv.Level == psaapi.LevelPrivileged {
+		return nil, nil
+	}
+
+	evaluator, err := getPodSecurityEvaluator()
+	if err != nil {
+		return nil, err
+	}
+
+	violations := toPodSecurityViolations("", evaluator.EvaluatePod(lv, &pod.ObjectMeta, &pod.Spec))
+	for idx := range containers {
+		for _, violation := range evaluateEphemeralContainer(evaluator, lv, pod, &containers[idx]) {
+			// Pod-level settings (e.g. spec.securityContext.sysctls) are already reported for the pod
+			if !slices.ContainsFunc(violations, func(v PodSecurityViolation) bool {
+				return len(v.Container) == 0 && v.Reason == violation.Reason && v.Detail == violation.Detail
+			}) {
+				violations = append(violations, violation)
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// Get a variant of the ephemeral container that complies with the Pod Security level and version
+// Only the container's security context is adjusted. Return an error if no compliant variant exists
+// (e.g. the pod itself violates the policy)
+func CompliantEphemeralContainer(lv psaapi.LevelVersion, pod *corev1.Pod, container *corev1.EphemeralContainer) (*corev1.EphemeralContainer, error) {
+	adjusted := container.DeepCopy()
+	if lv.Level == psaapi.LevelPrivileged {
+		return adjusted, nil
+	}
+
+	if adjusted.SecurityContext == nil {
+		adjusted.SecurityContext = &corev1.SecurityContext{}
+	}
+	securityContext := adjusted.SecurityContext
+
+	allowedCapabilities := baselineCapabilities
+	if lv.Level == psaapi.LevelRestricted {
+		allowedCapabilities = restrictedCapabilities
+	}
+
+	securityContext.Privileged = nil
+	securityContext.ProcMount = nil
+	if securityContext.Capabilities != nil {
+		securityContext.Capabilities.Add = slices.DeleteFunc(securityContext.Capabilities.Add, func(c corev1.Capability) bool {
+			return !slices.Contains(allowedCapabilities, c)
+		})
+	}
+	if securityContext.WindowsOptions != nil {
+		securityContext.WindowsOptions.HostProcess = nil
+	}
+	if securityContext.SELinuxOptions != nil {
+		securityContext.SELinuxOptions.User, securityContext.SELinuxOptions.Role = "", ""
+		if !slices.Contains(baselineSELinuxTypes, securityContext.SELinuxOptions.Type) {
+			securityContext.SELinuxOptions.Type = ""
+		}
+	}
+	if securityContext.SeccompProfile != nil && securityContext.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+		securityContext.SeccompProfile = nil
+	}
+	if securityContext.AppArmorProfile != nil && securityContext.AppArmorProfile.Type == corev1.AppArmorProfileTypeUnconfined {
+		securityContext.AppArmorProfile = nil
+	}
+
+	if lv.Level == psaapi.LevelRestricted {
+		securityContext.AllowPrivilegeEscalation = ptr.To(false)
+		if securityContext.Capabilities == nil {
+			securityContext.Capabilities = &corev1.Capabilities{}
+		}
+		securityContext.Capabilities.Drop = []corev1.Capability{"ALL"}
+
+		// Images often run as root by default, so a non-root user is set unless one is configured
+		podSecurityContext := pod.Spec.SecurityContext
+		if podSecurityContext == nil {
+			podSecurityContext = &corev1.PodSecurityContext{}
+		}
+		securityContext.RunAsNonRoot = ptr.To(true)
+		if runAsUser := ptr.Deref(securityContext.RunAsUser, ptr.Deref(podSecurityContext.RunAsUser, 0)); runAsUser == 0 {
+			securityContext.RunAsUser = ptr.To(NOBODY_UID)
+		}
+		if securityContext.SeccompProfile == nil && podSecurityContext.SeccompProfile == nil {
+			securityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+		}
+	}
+
+	// Drop the fields left empty by the adjustments
+	if securityContext.Capabilities != nil && len(securityContext.Capabilities.Add) == 0 && len(securityContext.Capabilities.Drop) == 0 {
+		securityContext.Capabilities = nil
+	}
+	if *securityContext == (corev1.SecurityContext{}) {
+		adjusted.SecurityContext = nil
+	}
+
+	violations, err := EvaluatePodSecurity(lv, pod, []corev1.EphemeralContainer{*adjusted})
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) > 0 {
+		return nil, fmt.Errorf("no variant of ephemeral container %s complies with Pod Security %q", container.Name, lv.String())
+	}
+
+	return adjusted, nil
+}
+
+// Evaluate an ephemeral container on its own
+// Only the pod-level settings inherited by containers are kept so that other containers do not cause violations
+func evaluateEphemeralContainer(evaluator policy.Evaluator, lv psaapi.LevelVersion, pod *corev1.Pod, container *corev1.EphemeralContainer) []PodSecurityViolation {
+	spec := &corev1.PodSpec{
+		SecurityContext:     pod.Spec.SecurityContext,
+		OS:                  pod.Spec.OS,
+		HostUsers:           pod.Spec.HostUsers,
+		EphemeralContainers: []corev1.EphemeralContainer{*container},
+	}
+
+	return toPodSecurityViolations(container.Name, evaluator.EvaluatePod(lv, &pod.ObjectMeta, spec))
+}
+
+func toPodSecurityViolations(container string, results []policy.CheckResult) []PodSecurityViolation {
+	var violations []PodSecurityViolation
+	for _, result := range results {
+		if !result.Allowed {
+			violations = append(violations, PodSecurityViolation{
+				Container: container,
+				Reason:    result.ForbiddenReason,
+				Detail:    result.ForbiddenDetail,
+			})
+		}
+	}
+	return violations
+}
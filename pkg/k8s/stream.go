@@ -0,0 +1,192 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/kubectl/pkg/util/term"
+)
+
+// Options to stream to and from a container (i.e. attach, exec)
+type StreamOptions struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Command to execute. Only applicable to exec
+	Command []string
+
+	// Pass stdin to the container
+	Stdin bool
+	// Stdin is a TTY
+	TTY bool
+
+	In     io.Reader
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+// Attach to a running ephemeral container
+func (client *KubeClientset) Attach(ctx context.Context, opts *StreamOptions) error {
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("attach").
+		VersionedParams(&corev1.PodAttachOptions{
+			Container: opts.Container,
+			Stdin:     opts.Stdin,
+			Stdout:    opts.Out != nil,
+			Stderr:    opts.ErrOut != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	return client.stream(ctx, req.URL(), opts)
+}
+
+// Execute a command in a running ephemeral container
+func (client *KubeClientset) Exec(ctx context.Context, opts *StreamOptions) error {
+	if len(opts.Command) == 0 {
+		return errors.New("command is required for exec")
+	}
+
+	req := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(opts.Namespace).
+		Name(opts.Pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: opts.Container,
+			Command:   opts.Command,
+			Stdin:     opts.Stdin,
+			Stdout:    opts.Out != nil,
+			Stderr:    opts.ErrOut != nil && !opts.TTY,
+			TTY:       opts.TTY,
+		}, scheme.ParameterCodec)
+
+	return client.stream(ctx, req.URL(), opts)
+}
+
+// Stream stdin/stdout/stderr to and from the container
+// If TTY is requested, the local terminal is set to raw mode and window resizes are propagated
+func (client *KubeClientset) stream(ctx context.Context, url *url.URL, opts *StreamOptions) error {
+	if client.Config == nil {
+		return errors.New("REST config is required for streaming")
+	}
+
+	executor, err := newExecutor(client.Config, url)
+	if err != nil {
+		return err
+	}
+
+	tty := term.TTY{
+		In:  opts.In,
+		Out: opts.Out,
+		Raw: opts.TTY,
+	}
+
+	streamOpts := remotecommand.StreamOptions{
+		Stdout: opts.Out,
+		Tty:    opts.TTY,
+	}
+
+	if opts.Stdin {
+		streamOpts.Stdin = opts.In
+	}
+
+	if opts.TTY {
+		// Stderr is merged into stdout with a TTY
+		streamOpts.TerminalSizeQueue = tty.MonitorSize(tty.GetSize())
+	} else {
+		streamOpts.Stderr = opts.ErrOut
+	}
+
+	return tty.Safe(func() error {
+		return executor.StreamWithContext(ctx, streamOpts)
+	})
+}
+
+// Create an executor with SPDY, falling back to WebSocket if the upgrade fails
+func newExecutor(config *rest.Config, url *url.URL) (remotecommand.Executor, error) {
+	spdyExecutor, err := remotecommand.NewSPDYExecutor(config, http.MethodPost, url)
+	if err != nil {
+		return nil, err
+	}
+
+	websocketExecutor, err := remotecommand.NewWebSocketExecutor(config, http.MethodGet, url.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return remotecommand.NewFallbackExecutor(spdyExecutor, websocketExecutor, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+}
+
+// Check if the input is a terminal
+func IsTerminal(in io.Reader) bool {
+	return term.TTY{In: in}.IsTerminalIn()
+}
+
+// Select the running ephemeral container of a pod if there is exactly one
+func SelectRunningEphemeralContainer(pod *corev1.Pod) (string, error) {
+	var running []string
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.State.Running != nil {
+			running = append(running, status.Name)
+		}
+	}
+
+	switch len(running) {
+	case 1:
+		return running[0], nil
+	case 0:
+		return "", fmt.Errorf("no running ephemeral containers found in pod/%s", pod.Name)
+	default:
+		return "", fmt.Errorf("multiple running ephemeral containers found in pod/%s. Specify one with --container: %s", pod.Name, strings.Join(running, ","))
+	}
+}
+
+// Check that the ephemeral container exists in the pod and is running
+func ValidateRunningEphemeralContainer(pod *corev1.Pod, name string) error {
+	if FindEphemeralContainer(pod, name) == nil {
+		return fmt.Errorf("ephemeral container %s not found in pod/%s", name, pod.Name)
+	}
+
+	status := findEphemeralContainerStatus(pod, name)
+	if status == nil || status.State.Running == nil {
+		return fmt.Errorf("ephemeral container %s in pod/%s is not running", name, pod.Name)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// Condition of an ephemeral container to wait for
+type ContainerCondition string
+
+const (
+	ConditionRunning    ContainerCondition = "running"
+	ConditionTerminated ContainerCondition = "terminated"
+)
+
+var (
+	// Waiting reasons that indicate a container is unlikely to start without user intervention
+	failedWaitingReasons = map[string]bool{
+		"ErrImagePull":               true,
+		"ImagePullBackOff":           true,
+		"ErrImageNeverPull":          true,
+		"InvalidImageName":           true,
+		"CreateContainerConfigError": true,
+		"CreateContainerError":       true,
+		"RunContainerError":          true,
+	}
+)
+
+// Parse a container condition from string
+func ParseContainerCondition(condition string) (ContainerCondition, error) {
+	switch c := ContainerCondition(condition); c {
+	case ConditionRunning, ConditionTerminated:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unsupported condition %q. One of: %s, %s", condition, ConditionRunning, ConditionTerminated)
+	}
+}
+
+// Watch the pod until its ephemeral container reaches the condition
+// The wait is bounded by the context (i.e. timeout and signals)
+func (client *KubeClientset) WaitForEphemeralContainer(ctx context.Context, namespace, podName, containerName string, condition ContainerCondition) (*corev1.ContainerStatus, error) {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", podName).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Pods(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return client.CoreV1().Pods(namespace).Watch(ctx, options)
+		},
+	}
+
+	var lastStatus *corev1.ContainerStatus
+	_, err := watchtools.UntilWithSync(ctx, lw, &corev1.Pod{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, fmt.Errorf("pod/%s was deleted", podName)
+		}
+
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok {
+			return false, nil
+		}
+
+		if FindEphemeralContainer(pod, containerName) == nil {
+			return false, fmt.Errorf("ephemeral container %s not found in pod/%s", containerName, podName)
+		}
+
+		status := findEphemeralContainerStatus(pod, containerName)
+		if status == nil {
+			return false, nil
+		}
+		lastStatus = status
+
+		return checkContainerCondition(status, condition)
+	})
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return lastStatus, errors.Join(fmt.Errorf("ephemeral container %s in pod/%s did not become %s%s", containerName, podName, condition, describeWaitingReason(lastStatus)), ctx.Err())
+		}
+		return lastStatus, err
+	}
+
+	return lastStatus, nil
+}
+
+// Check if the container status meets the condition
+// An error is returned if the condition can no longer be met
+func checkContainerCondition(status *corev1.ContainerStatus, condition ContainerCondition) (bool, error) {
+	state := status.State
+
+	if state.Waiting != nil && failedWaitingReasons[state.Waiting.Reason] {
+		return false, fmt.Errorf("ephemeral container %s failed to start%s", status.Name, describeWaitingReason(status))
+	}
+
+	switch condition {
+	case ConditionRunning:
+		if state.Running != nil {
+			return true, nil
+		}
+		if state.Terminated != nil {
+			return false, fmt.Errorf("ephemeral container %s terminated with exit code %d (%s)", status.Name, state.Terminated.ExitCode, state.Terminated.Reason)
+		}
+	case ConditionTerminated:
+		if state.Terminated != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Describe the waiting reason (if any) of a container status
+func describeWaitingReason(status *corev1.ContainerStatus) string {
+	if status == nil || status.State.Waiting == nil || len(status.State.Waiting.Reason) == 0 {
+		return ""
+	}
+
+	if len(status.State.Waiting.Message) > 0 {
+		return fmt.Sprintf(": %s: %s", status.State.Waiting.Reason, status.State.Waiting.Message)
+	}
+	return fmt.Sprintf(": %s", status.State.Waiting.Reason)
+}
+
+// Find the status of an ephemeral container by name
+func findEphemeralContainerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for idx := range pod.Status.EphemeralContainerStatuses {
+		if pod.Status.EphemeralContainerStatuses[idx].Name == name {
+			return &pod.Status.EphemeralContainerStatuses[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Kinds of resources that can be given as arguments
+const (
+	KindPod         string = "pod"
+	KindDeployment  string = "deployment"
+	KindStatefulSet string = "statefulset"
+	KindDaemonSet   string = "daemonset"
+	KindJob         string = "job"
+	KindReplicaSet  string = "replicaset"
+)
+
+var (
+	// Supported kinds by their names, plurals and short names
+	resourceKinds = map[string]string{
+		"pod": KindPod, "pods": KindPod, "po": KindPod,
+		"deployment": KindDeployment, "deployments": KindDeployment, "deploy": KindDeployment,
+		"statefulset": KindStatefulSet, "statefulsets": KindStatefulSet, "sts": KindStatefulSet,
+		"daemonset": KindDaemonSet, "daemonsets": KindDaemonSet, "ds": KindDaemonSet,
+		"job": KindJob, "jobs": KindJob,
+		"replicaset": KindReplicaSet, "replicasets": KindReplicaSet, "rs": KindReplicaSet,
+	}
+)
+
+// Reference to a pod or a workload from CLI arguments
+type ResourceRef struct {
+	Kind string
+	Name string
+}
+
+func (r *ResourceRef) String() string {
+	return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+}
+
+// Check if the reference is a pod rather than a workload
+func (r *ResourceRef) IsPod() bool {
+	return r.Kind == KindPod
+}
+
+// Get a resource reference from CLI arguments
+// Format: "kind/name", "kind name" or "name" (i.e. a pod)
+// Kind is one of pod, deployment, statefulset, daemonset, job or replicaset (or their plurals and short names)
+func GetResourceRefFromArgs(args []string) (*ResourceRef, error) {
+	var kind, name string
+
+	switch len(args) {
+	case 1:
+		parts := strings.Split(args[0], "/")
+		if len(parts) > 2 {
+			return nil, errors.New("single argument must have format: \"kind/name\"")
+		} else if len(parts) == 1 { // Assume pod-name
+			return &ResourceRef{Kind: KindPod, Name: parts[0]}, nil
+		}
+		kind, name = parts[0], parts[1]
+	case 2:
+		kind, name = args[0], args[1]
+	default:
+		return nil, errors.New("invalid number of arguments. Expect 1 or 2 arguments: \"kind/name\", or \"kind name\"")
+	}
+
+	resolved, found := resourceKinds[strings.ToLower(kind)]
+	if !found {
+		return nil, fmt.Errorf("unsupported resource type %q. One of: pod, deployment, statefulset, daemonset, job, replicaset", kind)
+	}
+
+	return &ResourceRef{Kind: resolved, Name: name}, nil
+}
+
+// Get the pods managed by a workload in a namespace
+// Pods are listed by the workload's selector, then filtered by controller references
+// Pods of Deployments are owned via ReplicaSets
+func (client *KubeClientset) GetPodsForWorkload(ctx context.Context, namespace string, ref *ResourceRef) ([]corev1.Pod, error) {
+	owner, selector, err := client.getWorkload(ctx, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := map[types.UID]bool{owner: true}
+	if ref.Kind == KindDeployment {
+		if owners, err = client.getReplicaSetsOwnedBy(ctx, namespace, owner, selector); err != nil {
+			return nil, err
+		}
+	}
+
+	pods, err := client.ListPods(ctx, namespace, metav1.ListOptions{LabelSelector: selector.String(), Limit: DEFAULT_CHUNK_SIZE}, func(pod corev1.Pod) bool {
+		controllerRef := metav1.GetControllerOf(&pod)
+		return controllerRef != nil && owners[controllerRef.UID]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Items of a list response have no TypeMeta (e.g. the manifest opened in the editor needs them)
+	for idx := range pods {
+		setGVK(&pods[idx])
+	}
+	return pods, nil
+}
+
+// Get the UID and pod selector of a workload
+func (client *KubeClientset) getWorkload(ctx context.Context, namespace string, ref *ResourceRef) (types.UID, labels.Selector, error) {
+	var meta metav1.Object
+	var labelSelector *metav1.LabelSelector
+
+	switch ref.Kind {
+	case KindDeployment:
+		deploy, err := client.AppsV1().Deployments(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		meta, labelSelector = deploy, deploy.Spec.Selector
+	case KindStatefulSet:
+		sts, err := client.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		meta, labelSelector = sts, sts.Spec.Selector
+	case KindDaemonSet:
+		ds, err := client.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		meta, labelSelector = ds, ds.Spec.Selector
+	case KindReplicaSet:
+		rs, err := client.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		meta, labelSelector = rs, rs.Spec.Selector
+	case KindJob:
+		job, err := client.BatchV1().Jobs(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, err
+		}
+		meta, labelSelector = job, job.Spec.Selector
+	default:
+		return "", nil, fmt.Errorf("unsupported workload %s", ref)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return "", nil, errors.Join(fmt.Errorf("invalid selector for %s", ref), err)
+	}
+
+	return meta.GetUID(), selector, nil
+}
+
+// Get the UIDs of ReplicaSets controlled by a Deployment
+func (client *KubeClientset) getReplicaSetsOwnedBy(ctx context.Context, namespace string, owner types.UID, selector labels.Selector) (map[types.UID]bool, error) {
+	rsList, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[types.UID]bool)
+	for idx := range rsList.Items {
+		if controllerRef := metav1.GetControllerOf(&rsList.Items[idx]); controllerRef != nil && controllerRef.UID == owner {
+			owners[rsList.Items[idx].UID] = true
+		}
+	}
+
+	return owners, nil
+}
+
+// Select the newest ready pod. If no pod is ready, the newest pod is selected
+func SelectNewestReadyPod(pods []corev1.Pod) (*corev1.Pod, error) {
+	if len(pods) == 0 {
+		return nil, errors.New("no pods found")
+	}
+
+	sorted := SortPodsByNewest(pods)
+	for idx := range sorted {
+		if IsPodReady(&sorted[idx]) {
+			return &sorted[idx], nil
+		}
+	}
+
+	return &sorted[0], nil
+}
+
+// Get a copy of pods sorted by creation time (newest first)
+func SortPodsByNewest(pods []corev1.Pod) []corev1.Pod {
+	sorted := append([]corev1.Pod{}, pods...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j].CreationTimestamp.Before(&sorted[i].CreationTimestamp)
+	})
+	return sorted
+}
+
+// Check if a pod has condition Ready
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -23,8 +23,12 @@
 package out
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	klog "k8s.io/klog/v2"
 )
@@ -35,6 +39,13 @@ var (
 
 	// destination where error output is sent
 	errFile io.Writer
+
+	// source where input is read
+	inFile io.Reader
+
+	// buffered reader around inFile, shared by all prompts so that
+	// input buffered by one prompt is not lost for the next ones (e.g. piped answers)
+	inReader *bufio.Reader
 )
 
 // Set outFile to an io.Writer
@@ -57,6 +68,69 @@ func GetErrFile() io.Writer {
 	return errFile
 }
 
+// Set inFile to an io.Reader
+func SetInFile(file io.Reader) {
+	inFile = file
+	inReader = nil
+	if file != nil {
+		inReader = bufio.NewReader(file)
+	}
+}
+
+// Get inFile
+func GetInFile() io.Reader {
+	return inFile
+}
+
+// Ask a yes/no question on stdout and read the answer from stdin
+// Only "y" or "yes" (case-insensitive) is a confirmation. Any other answer (including EOF) is a refusal
+func Confirm(question string) (bool, error) {
+	Stringf("%s [y/N]: ", question)
+
+	if inFile == nil {
+		klog.Errorf("[unset inFile]: no answer for %q", question)
+		return false, nil
+	}
+
+	answer, err := inReader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Ask to choose one of the options on stdout and read the answer (a 1-based index) from stdin
+// Return the 0-based index of the chosen option
+func Choose(question string, options []string) (int, error) {
+	for idx, option := range options {
+		Ln("%d) %s", idx+1, option)
+	}
+	Stringf("%s [1-%d]: ", question, len(options))
+
+	if inFile == nil {
+		klog.Errorf("[unset inFile]: no answer for %q", question)
+		return -1, errors.New("no answer provided")
+	}
+
+	answer, err := inReader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return -1, err
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || choice < 1 || choice > len(options) {
+		return -1, fmt.Errorf("invalid choice %q, must be a number between 1 and %d", strings.TrimSpace(answer), len(options))
+	}
+
+	return choice - 1, nil
+}
+
 // Write a formatted string with a newline to stdout
 func Stringf(format string, a ...interface{}) {
 	// Flush log to ensure correct log order
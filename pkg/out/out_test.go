@@ -24,6 +24,7 @@ package out_test
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/out"
 	. "github.com/onsi/ginkgo/v2"
@@ -73,6 +74,73 @@ var _ = Describe("Out", func() {
 			})
 		})
 	})
+
+	Context("when asking for confirmation", func() {
+		JustBeforeEach(func() {
+			out.SetOutFile(t.f)
+		})
+
+		DescribeTable("should read the answer", func(answer string, expected bool) {
+			out.SetInFile(strings.NewReader(answer))
+			Expect(out.GetInFile()).ToNot(BeNil())
+
+			confirmed, err := out.Confirm("Apply these changes?")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confirmed).To(Equal(expected))
+			t.expectContent("Apply these changes? [y/N]: ")
+		},
+			Entry("with y", "y\n", true),
+			Entry("with YES", "YES\n", true),
+			Entry("with n", "n\n", false),
+			Entry("with an empty line", "\n", false),
+			Entry("with EOF", "", false),
+		)
+	})
+
+	Context("when asking for a choice", func() {
+		JustBeforeEach(func() {
+			out.SetOutFile(t.f)
+		})
+
+		It("should return the chosen index", func() {
+			out.SetInFile(strings.NewReader("2\n"))
+
+			choice, err := out.Choose("Select a Pod", []string{"web-1", "web-2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(choice).To(Equal(1))
+			t.expectContent("1) web-1\n2) web-2\nSelect a Pod [1-2]: ")
+		})
+
+		DescribeTable("should fail with an invalid answer", func(answer string) {
+			out.SetInFile(strings.NewReader(answer))
+
+			_, err := out.Choose("Select a Pod", []string{"web-1", "web-2"})
+			Expect(err).To(HaveOccurred())
+		},
+			Entry("with a non-number", "web-1\n"),
+			Entry("with 0", "0\n"),
+			Entry("with an out-of-range number", "3\n"),
+			Entry("with EOF", ""),
+		)
+	})
+
+	Context("when asking several questions", func() {
+		JustBeforeEach(func() {
+			out.SetOutFile(t.f)
+		})
+
+		It("should read each answer from the same input", func() {
+			out.SetInFile(strings.NewReader("2\ny\n"))
+
+			choice, err := out.Choose("Select a Pod", []string{"web-1", "web-2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(choice).To(Equal(1))
+
+			confirmed, err := out.Confirm("Apply these changes?")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confirmed).To(BeTrue())
+		})
+	})
 })
 
 // Input for test cases
@@ -0,0 +1,86 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package profile
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// Built-in profiles with the same security settings as "kubectl debug --profile" for ephemeral containers
+// See: https://kubernetes.io/docs/tasks/debug/debug-application/debug-running-pod/#debugging-profiles
+const (
+	// Allow process tracing (i.e. SYS_PTRACE)
+	ProfileGeneral string = "general"
+	// No privileges beyond the container runtime defaults
+	ProfileBaseline string = "baseline"
+	// Run as non-root with all capabilities dropped, no privilege escalation and the RuntimeDefault seccomp profile
+	ProfileRestricted string = "restricted"
+	// Allow network administration (i.e. NET_ADMIN and NET_RAW)
+	ProfileNetadmin string = "netadmin"
+	// Run as privileged
+	ProfileSysadmin string = "sysadmin"
+)
+
+var (
+	builtinProfiles = map[string]Profile{
+		ProfileGeneral: {
+			SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"SYS_PTRACE"}},
+			},
+		},
+		// An empty security context clears the one set by earlier profiles
+		ProfileBaseline: {
+			SecurityContext: &corev1.SecurityContext{},
+		},
+		ProfileRestricted: {
+			SecurityContext: &corev1.SecurityContext{
+				RunAsNonRoot:             ptr.To(true),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				AllowPrivilegeEscalation: ptr.To(false),
+				SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+		},
+		ProfileNetadmin: {
+			SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN", "NET_RAW"}},
+			},
+		},
+		ProfileSysadmin: {
+			SecurityContext: &corev1.SecurityContext{
+				Privileged: ptr.To(true),
+			},
+		},
+	}
+)
+
+// List the built-in profile names in alphabetical order
+func ListBuiltinProfileNames() []string {
+	names := make([]string, 0, len(builtinProfiles))
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
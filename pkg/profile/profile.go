@@ -0,0 +1,295 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ENV_CONFIG          string = "KUBECTL_EPHEMERAL_CONTAINERS_CONFIG"
+	DEFAULT_CONFIG_FILE string = "ephemeral-containers.yaml" // Under $HOME/.kube
+)
+
+// Policy to select the target container of an ephemeral container
+type TargetPolicy string
+
+const (
+	// No target container (default)
+	TargetPolicyNone TargetPolicy = "none"
+	// Target the first container in the pod
+	TargetPolicyFirst TargetPolicy = "first"
+	// Target the container named in the profile
+	TargetPolicyNamed TargetPolicy = "named"
+)
+
+// Represent a named debug profile to expand into an ephemeral container
+type Profile struct {
+	Image           string                  `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy       `json:"imagePullPolicy,omitempty"`
+	Command         []string                `json:"command,omitempty"`
+	Args            []string                `json:"args,omitempty"`
+	Env             []corev1.EnvVar         `json:"env,omitempty"`
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	VolumeMounts    []corev1.VolumeMount    `json:"volumeMounts,omitempty"`
+	Stdin           bool                    `json:"stdin,omitempty"`
+	TTY             bool                    `json:"tty,omitempty"`
+	TargetPolicy    TargetPolicy            `json:"targetPolicy,omitempty"`
+	// Name of the target container if target policy is "named"
+	Target string `json:"target,omitempty"`
+}
+
+// Represent the plugin configuration file
+type Config struct {
+	// Settings applied to all profiles
+	Defaults Profile `json:"defaults,omitempty"`
+	// Named profiles
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Get the path to the configuration file
+// Precedence:
+// * KUBECTL_EPHEMERAL_CONTAINERS_CONFIG env var
+// * $HOME/.kube/ephemeral-containers.yaml
+func GetConfigPath() (string, error) {
+	if path := os.Getenv(ENV_CONFIG); len(path) > 0 {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".kube", DEFAULT_CONFIG_FILE), nil
+}
+
+// Load the configuration from a file
+// If the file does not exist, an empty configuration is returned
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+
+	config := &Config{}
+	if err = yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, errors.Join(fmt.Errorf("failed to parse config file %s", path), err)
+	}
+
+	for name, profile := range config.Profiles {
+		if err = profile.Validate(); err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid profile %s in config file %s", name, path), err)
+		}
+	}
+
+	return config, nil
+}
+
+// List the profile names in alphabetical order
+func (config *Config) ListProfileNames() []string {
+	names := make([]string, 0, len(config.Profiles))
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get a profile by name, merged with defaults
+func (config *Config) GetProfile(name string) (*Profile, error) {
+	return config.ResolveProfiles(name)
+}
+
+// Resolve profiles by names into a single profile, merged with defaults
+// Later profiles take precedence over earlier ones
+// Profiles in the config file take precedence over built-in profiles with the same name
+func (config *Config) ResolveProfiles(names ...string) (*Profile, error) {
+	result := config.Defaults.DeepCopy()
+	for _, name := range names {
+		profile, ok := config.Profiles[name]
+		if !ok {
+			if profile, ok = builtinProfiles[name]; !ok {
+				return nil, fmt.Errorf("profile %s not found", name)
+			}
+		}
+		result = MergeProfiles(*result, profile)
+	}
+	return result, nil
+}
+
+// Validate the profile
+func (profile *Profile) Validate() error {
+	switch profile.TargetPolicy {
+	case "", TargetPolicyNone, TargetPolicyFirst:
+	case TargetPolicyNamed:
+		if len(profile.Target) == 0 {
+			return fmt.Errorf("target is required for target policy %s", TargetPolicyNamed)
+		}
+	default:
+		return fmt.Errorf("unsupported target policy %q. One of: %s, %s, %s", profile.TargetPolicy, TargetPolicyNone, TargetPolicyFirst, TargetPolicyNamed)
+	}
+	return nil
+}
+
+// Get a deep copy of the profile
+func (profile *Profile) DeepCopy() *Profile {
+	result := *profile
+	result.Command = append([]string(nil), profile.Command...)
+	result.Args = append([]string(nil), profile.Args...)
+	result.Env = mergeEnvVars(nil, profile.Env)
+	result.VolumeMounts = mergeVolumeMounts(nil, profile.VolumeMounts)
+	if profile.SecurityContext != nil {
+		result.SecurityContext = profile.SecurityContext.DeepCopy()
+	}
+	return &result
+}
+
+// Merge 2 profiles. Fields set in override take precedence over base
+// Environment variables and volume mounts are merged by name and mount path respectively
+func MergeProfiles(base, override Profile) *Profile {
+	result := base.DeepCopy()
+
+	if len(override.Image) > 0 {
+		result.Image = override.Image
+	}
+	if len(override.ImagePullPolicy) > 0 {
+		result.ImagePullPolicy = override.ImagePullPolicy
+	}
+	if len(override.Command) > 0 {
+		result.Command = append([]string(nil), override.Command...)
+	}
+	if len(override.Args) > 0 {
+		result.Args = append([]string(nil), override.Args...)
+	}
+	if override.SecurityContext != nil {
+		result.SecurityContext = override.SecurityContext.DeepCopy()
+	}
+	if len(override.TargetPolicy) > 0 {
+		result.TargetPolicy = override.TargetPolicy
+		result.Target = override.Target
+	}
+
+	result.Stdin = result.Stdin || override.Stdin
+	result.TTY = result.TTY || override.TTY
+	result.Env = mergeEnvVars(result.Env, override.Env)
+	result.VolumeMounts = mergeVolumeMounts(result.VolumeMounts, override.VolumeMounts)
+
+	return result
+}
+
+// Expand the profile into the ephemeral container for the pod
+// Fields already set in the container (e.g. from command-line flags) take precedence
+func (profile *Profile) Apply(container *corev1.EphemeralContainer, pod *corev1.Pod) error {
+	if len(container.Image) == 0 {
+		container.Image = profile.Image
+	}
+	if len(container.ImagePullPolicy) == 0 {
+		container.ImagePullPolicy = profile.ImagePullPolicy
+	}
+	if len(container.Command) == 0 && len(container.Args) == 0 {
+		container.Command = append([]string(nil), profile.Command...)
+		container.Args = append([]string(nil), profile.Args...)
+	}
+	// An empty security context (e.g. the built-in "baseline" profile) leaves it unset
+	if container.SecurityContext == nil && profile.SecurityContext != nil && *profile.SecurityContext != (corev1.SecurityContext{}) {
+		container.SecurityContext = profile.SecurityContext.DeepCopy()
+	}
+
+	container.Stdin = container.Stdin || profile.Stdin
+	container.TTY = container.TTY || profile.TTY
+	container.Env = mergeEnvVars(profile.Env, container.Env)
+	container.VolumeMounts = mergeVolumeMounts(profile.VolumeMounts, container.VolumeMounts)
+
+	if len(container.TargetContainerName) == 0 {
+		target, err := profile.getTarget(pod)
+		if err != nil {
+			return err
+		}
+		container.TargetContainerName = target
+	}
+
+	return nil
+}
+
+// Get the target container name for the pod by the target policy
+func (profile *Profile) getTarget(pod *corev1.Pod) (string, error) {
+	switch profile.TargetPolicy {
+	case TargetPolicyFirst:
+		if len(pod.Spec.Containers) == 0 {
+			return "", fmt.Errorf("no containers found in pod/%s to target", pod.Name)
+		}
+		return pod.Spec.Containers[0].Name, nil
+	case TargetPolicyNamed:
+		return profile.Target, nil
+	default:
+		return "", nil
+	}
+}
+
+// Merge environment variables by name. Variables in override take precedence
+func mergeEnvVars(base, override []corev1.EnvVar) []corev1.EnvVar {
+	var result []corev1.EnvVar
+	indexes := make(map[string]int)
+
+	for _, vars := range [][]corev1.EnvVar{base, override} {
+		for _, v := range vars {
+			if idx, ok := indexes[v.Name]; ok {
+				result[idx] = *v.DeepCopy()
+				continue
+			}
+			indexes[v.Name] = len(result)
+			result = append(result, *v.DeepCopy())
+		}
+	}
+
+	return result
+}
+
+// Merge volume mounts by mount path. Mounts in override take precedence
+func mergeVolumeMounts(base, override []corev1.VolumeMount) []corev1.VolumeMount {
+	var result []corev1.VolumeMount
+	indexes := make(map[string]int)
+
+	for _, mounts := range [][]corev1.VolumeMount{base, override} {
+		for _, m := range mounts {
+			if idx, ok := indexes[m.MountPath]; ok {
+				result[idx] = *m.DeepCopy()
+				continue
+			}
+			indexes[m.MountPath] = len(result)
+			result = append(result, *m.DeepCopy())
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,250 @@
+// MIT License
+
+// Copyright (c) 2024 k8s-crafts Authors
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package profile_test
+
+import (
+	"os"
+	"path"
+
+	"github.com/k8s-crafts/ephemeral-containers-plugin/pkg/profile"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/cmd/debug"
+)
+
+var _ = Describe("Profile", func() {
+	var t *test
+
+	BeforeEach(func() {
+		t = newTest()
+	})
+
+	Context("when getting config path", func() {
+		AfterEach(func() {
+			Expect(os.Unsetenv(profile.ENV_CONFIG)).ToNot(HaveOccurred())
+		})
+
+		It("should use the env var if set", func() {
+			Expect(os.Setenv(profile.ENV_CONFIG, t.configPath)).ToNot(HaveOccurred())
+
+			configPath, err := profile.GetConfigPath()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configPath).To(Equal(t.configPath))
+		})
+
+		It("should default to a file under $HOME/.kube", func() {
+			configPath, err := profile.GetConfigPath()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configPath).To(HaveSuffix(path.Join(".kube", profile.DEFAULT_CONFIG_FILE)))
+		})
+	})
+
+	Context("when loading config", func() {
+		It("should parse profiles", func() {
+			config, err := profile.LoadConfig(t.configPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.ListProfileNames()).To(Equal([]string{"busybox", "netshoot"}))
+		})
+
+		It("should return an empty config if the file does not exist", func() {
+			config, err := profile.LoadConfig(path.Join("testdata", "not-a-file.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Profiles).To(BeEmpty())
+		})
+	})
+
+	Context("when getting a profile", func() {
+		var config *profile.Config
+
+		BeforeEach(func() {
+			var err error
+			config, err = profile.LoadConfig(t.configPath)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should merge with defaults", func() {
+			p, err := config.GetProfile("netshoot")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Image).To(Equal("nicolaka/netshoot:v0.13"))
+			Expect(p.ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			Expect(p.Env).To(Equal([]corev1.EnvVar{{Name: "DEBUG", Value: "false"}}))
+		})
+
+		It("should merge multiple profiles in order", func() {
+			p, err := config.ResolveProfiles("netshoot", "busybox")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Image).To(Equal("busybox:1.28"))
+			Expect(p.Command).To(Equal([]string{"sleep", "infinity"}))
+			Expect(p.TargetPolicy).To(Equal(profile.TargetPolicyNamed))
+			Expect(p.VolumeMounts).To(HaveLen(1))
+			Expect(p.SecurityContext).ToNot(BeNil())
+		})
+
+		It("should fail if not found", func() {
+			_, err := config.GetProfile("not-a-profile")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when applying a profile", func() {
+		var p *profile.Profile
+
+		BeforeEach(func() {
+			config, err := profile.LoadConfig(t.configPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			p, err = config.GetProfile("netshoot")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should fill in unset fields", func() {
+			container := &corev1.EphemeralContainer{}
+			Expect(p.Apply(container, t.pod)).ToNot(HaveOccurred())
+
+			Expect(container.Image).To(Equal("nicolaka/netshoot:v0.13"))
+			Expect(container.Command).To(Equal([]string{"sleep", "infinity"}))
+			Expect(container.TargetContainerName).To(Equal("app"))
+			Expect(container.Stdin).To(BeTrue())
+			Expect(container.TTY).To(BeTrue())
+			Expect(container.SecurityContext.Capabilities.Add).To(ConsistOf(corev1.Capability("NET_ADMIN"), corev1.Capability("NET_RAW")))
+		})
+
+		It("should not override fields already set", func() {
+			container := &corev1.EphemeralContainer{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Image:   "busybox:1.28",
+					Command: []string{"sh"},
+					Env:     []corev1.EnvVar{{Name: "DEBUG", Value: "1"}},
+				},
+				TargetContainerName: "sidecar",
+			}
+			Expect(p.Apply(container, t.pod)).ToNot(HaveOccurred())
+
+			Expect(container.Image).To(Equal("busybox:1.28"))
+			Expect(container.Command).To(Equal([]string{"sh"}))
+			Expect(container.Env).To(Equal([]corev1.EnvVar{{Name: "DEBUG", Value: "1"}}))
+			Expect(container.TargetContainerName).To(Equal("sidecar"))
+		})
+	})
+
+	Context("when using built-in profiles", func() {
+		DescribeTable("should have the same security settings as kubectl debug",
+			func(name string) {
+				p, err := (&profile.Config{}).GetProfile(name)
+				Expect(err).ToNot(HaveOccurred())
+
+				container := &corev1.EphemeralContainer{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger"}}
+				Expect(p.Apply(container, t.pod)).ToNot(HaveOccurred())
+
+				// kubectl applies profiles to ephemeral containers when the target is the pod itself
+				pod := t.pod.DeepCopy()
+				pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger"}}}
+				applier, err := debug.NewProfileApplier(name, debug.KeepFlags{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(applier.Apply(pod, "debugger", pod)).ToNot(HaveOccurred())
+
+				Expect(container.SecurityContext).To(Equal(pod.Spec.EphemeralContainers[0].SecurityContext))
+			},
+			Entry("general", profile.ProfileGeneral),
+			Entry("baseline", profile.ProfileBaseline),
+			Entry("restricted", profile.ProfileRestricted),
+			Entry("netadmin", profile.ProfileNetadmin),
+			Entry("sysadmin", profile.ProfileSysadmin),
+		)
+
+		It("should list built-in profiles", func() {
+			Expect(profile.ListBuiltinProfileNames()).To(Equal([]string{"baseline", "general", "netadmin", "restricted", "sysadmin"}))
+		})
+
+		It("should prefer profiles in the config file", func() {
+			config := &profile.Config{Profiles: map[string]profile.Profile{profile.ProfileNetadmin: {Image: "nicolaka/netshoot:v0.13"}}}
+
+			p, err := config.GetProfile(profile.ProfileNetadmin)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Image).To(Equal("nicolaka/netshoot:v0.13"))
+			Expect(p.SecurityContext).To(BeNil())
+		})
+
+		It("should combine with profiles in the config file", func() {
+			config, err := profile.LoadConfig(t.configPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			p, err := config.ResolveProfiles("netshoot", profile.ProfileBaseline)
+			Expect(err).ToNot(HaveOccurred())
+
+			container := &corev1.EphemeralContainer{}
+			Expect(p.Apply(container, t.pod)).ToNot(HaveOccurred())
+			Expect(container.Image).To(Equal("nicolaka/netshoot:v0.13"))
+			Expect(container.SecurityContext).To(BeNil())
+		})
+	})
+
+	Context("when validating a profile", func() {
+		It("should fail with unsupported target policy", func() {
+			p := &profile.Profile{TargetPolicy: "random"}
+			Expect(p.Validate()).ToNot(Succeed())
+		})
+
+		It("should fail with named target policy without target", func() {
+			p := &profile.Profile{TargetPolicy: profile.TargetPolicyNamed}
+			Expect(p.Validate()).ToNot(Succeed())
+		})
+	})
+})
+
+type testInput struct {
+	configPath string
+	pod        *corev1.Pod
+}
+
+type test struct {
+	*testInput
+}
+
+func newTest() *test {
+	return &test{
+		testInput: &testInput{
+			configPath: path.Join("testdata", "config.yaml"),
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "web",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "registry.k8s.io/pause:3.1",
+						},
+						{
+							Name:  "sidecar",
+							Image: "registry.k8s.io/pause:3.1",
+						},
+					},
+				},
+			},
+		},
+	}
+}